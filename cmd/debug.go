@@ -0,0 +1,35 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"go.uber.org/zap"
+	"net"
+	"net/http"
+	"time"
+)
+
+// runDebugServer serves handler on addr, the returned stop func blocks until it is shut down.
+func runDebugServer(addr string, handler http.Handler) (func(), error) {
+	listener, err := net.Listen("tcp", addr)
+	if err != nil {
+		return nil, err
+	}
+	server := &http.Server{Handler: handler}
+	serverDone := make(chan struct{})
+	go func() {
+		defer close(serverDone)
+		err := server.Serve(listener)
+		if err != nil && !errors.Is(err, http.ErrServerClosed) {
+			zap.L().Error("Debug Server Error", zap.Error(err))
+		}
+	}()
+	zap.L().Info("调试接口已启动", zap.String("addr", "http://"+listener.Addr().String()+"/debug/"))
+
+	return func() {
+		ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+		defer cancel()
+		_ = server.Shutdown(ctx)
+		<-serverDone
+	}, nil
+}
@@ -0,0 +1,146 @@
+package main
+
+import (
+	"IsaacCoyote/common/config/model"
+	"IsaacCoyote/common/game"
+	"IsaacCoyote/pkg/coyote"
+	"IsaacCoyote/pkg/coyote/enums"
+	"IsaacCoyote/util"
+	"context"
+	"fmt"
+	"go.uber.org/zap"
+	"sync"
+)
+
+// deviceManager keeps one coyote controller per configured device and
+// mirrors the `devices` list into the game on every config reload.
+type deviceManager struct {
+	lock        sync.Mutex
+	game        *game.Game
+	controllers map[string]coyote.Controller
+
+	// newController and removeController hide whether devices are sessions
+	// of the embedded server or clients of a remote relay
+	newController    func(name string) coyote.Controller
+	removeController func(name string, controller coyote.Controller) error
+	shutdown         func()
+}
+
+func (m *deviceManager) sync(devices []model.Device) {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+
+	if len(devices) == 0 {
+		devices = []model.Device{{Name: "default", ScaleA: 1, ScaleB: 1}}
+	}
+
+	configured := make(map[string]bool)
+	for i, device := range devices {
+		if device.Name == "" {
+			device.Name = fmt.Sprintf("device%d", i+1)
+		}
+		if configured[device.Name] {
+			zap.L().Error("设备名称重复, 已忽略", zap.String("device", device.Name))
+			continue
+		}
+		configured[device.Name] = true
+
+		controller, ok := m.controllers[device.Name]
+		if !ok {
+			controller = m.newController(device.Name)
+			m.controllers[device.Name] = controller
+		}
+		m.game.AddDevice(device.Name, controller, device)
+	}
+
+	for name, controller := range m.controllers {
+		if configured[name] {
+			continue
+		}
+		m.game.RemoveDevice(name)
+		err := m.removeController(name, controller)
+		if err != nil {
+			zap.L().Error("移除设备失败", zap.String("device", name), zap.Error(err))
+		}
+		delete(m.controllers, name)
+		zap.L().Info("已移除设备", zap.String("device", name))
+	}
+}
+
+// close stops every controller owned by the manager.
+func (m *deviceManager) close() {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+
+	m.shutdown()
+}
+
+func showQRCode(name string, controller coyote.Controller) {
+	zap.L().Info("等待连接...... 请使用使用 DG-LAB app 扫码二维码", zap.String("device", name))
+	_ = util.PrintTerminalQRCode(controller.GetQRCodeContent())
+	err := util.ShowQRCode(fmt.Sprintf("qrcode_%s.png", name), controller.GetQRCodeContent())
+	if err != nil {
+		zap.L().Error("获取二维码失败", zap.String("device", name), zap.Error(err))
+	}
+}
+
+// newEmbeddedDeviceManager creates a session on the embedded server for every device.
+func newEmbeddedDeviceManager(c *coyote.Coyote, g *game.Game) *deviceManager {
+	return &deviceManager{
+		game:        g,
+		controllers: make(map[string]coyote.Controller),
+		newController: func(name string) coyote.Controller {
+			session := c.NewSession()
+			showQRCode(name, session)
+			return session
+		},
+		removeController: func(name string, controller coyote.Controller) error {
+			return c.RemoveSession(controller.GetClientID())
+		},
+		// sessions are closed together with the embedded server
+		shutdown: func() {},
+	}
+}
+
+// newClientDeviceManager connects a client to the remote relay for every device,
+// clients run until ctx is cancelled or close is called. The relay hands out a new clientID on each reconnect, so the QR code is shown again.
+func newClientDeviceManager(ctx context.Context, config *coyote.Config, g *game.Game) *deviceManager {
+	var wg sync.WaitGroup
+	cancels := make(map[string]context.CancelFunc)
+
+	return &deviceManager{
+		game:        g,
+		controllers: make(map[string]coyote.Controller),
+		newController: func(name string) coyote.Controller {
+			client := coyote.NewCoyoteClient(config)
+			client.RegisterCallback(enums.OnSessionIDAssigned, func(controller coyote.Controller, _ coyote.CallbackData[any]) {
+				showQRCode(name, controller)
+			})
+
+			clientCtx, cancel := context.WithCancel(ctx)
+			cancels[name] = cancel
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				err := client.Run(clientCtx)
+				if err != nil {
+					zap.L().Error("Coyote Client Error", zap.String("device", name), zap.Error(err))
+				}
+			}()
+			return client
+		},
+		removeController: func(name string, _ coyote.Controller) error {
+			if cancel, ok := cancels[name]; ok {
+				cancel()
+				delete(cancels, name)
+			}
+			return nil
+		},
+		shutdown: func() {
+			for _, cancel := range cancels {
+				cancel()
+			}
+			wg.Wait()
+		},
+	}
+}
@@ -2,14 +2,19 @@ package main
 
 import (
 	"IsaacCoyote/common/config"
+	"IsaacCoyote/common/config/model"
 	"IsaacCoyote/common/game"
 	"IsaacCoyote/common/isaac"
 	"IsaacCoyote/common/logging"
 	"IsaacCoyote/pkg/coyote"
-	"IsaacCoyote/pkg/coyote/enums"
 	"IsaacCoyote/util"
+	"context"
 	"fmt"
 	"go.uber.org/zap"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
 )
 
 func main() {
@@ -17,6 +22,9 @@ func main() {
 	zap.ReplaceGlobals(logger)
 	defer fmt.Scanln()
 
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
 	configM, err := config.NewConfigManager("config.yaml")
 	if err != nil {
 		zap.L().Error("初始化配置失败", zap.Error(err))
@@ -37,59 +45,121 @@ func main() {
 	coyoteConfig := coyote.Config{
 		Address: configM.GetConfig().Coyote.Address,
 		Port:    configM.GetConfig().Coyote.Port,
+		TLS: coyote.TLSConfig{
+			Enabled:  configM.GetConfig().Coyote.TLS.Enabled,
+			CertFile: configM.GetConfig().Coyote.TLS.CertFile,
+			KeyFile:  configM.GetConfig().Coyote.TLS.KeyFile,
+		},
+		HeartbeatInterval: time.Duration(configM.GetConfig().Coyote.HeartbeatInterval) * time.Millisecond,
+		StaleTimeout:      time.Duration(configM.GetConfig().Coyote.StaleTimeout) * time.Millisecond,
+		EvictTimeout:      time.Duration(configM.GetConfig().Coyote.EvictTimeout) * time.Millisecond,
+		ResumeGrace:       time.Duration(configM.GetConfig().Coyote.ResumeGrace) * time.Millisecond,
+		RelayURL:          configM.GetConfig().Coyote.RelayURL,
+		ReconnectMinDelay: time.Duration(configM.GetConfig().Coyote.ReconnectMinDelay) * time.Millisecond,
+		ReconnectMaxDelay: time.Duration(configM.GetConfig().Coyote.ReconnectMaxDelay) * time.Millisecond,
+	}
+	if configM.GetConfig().Coyote.Mode == model.RELAY {
+		zap.L().Info("以中继模式运行", zap.Int("port", coyoteConfig.Port))
+		err = coyote.NewCoyoteRelay(&coyoteConfig).Run(ctx)
+		if err != nil {
+			zap.L().Error("Coyote Relay Error", zap.Error(err))
+		}
+		return
+	}
+
+	isaacListener := isaac.NewGameListener(&configM.GetConfig().Isaac)
+	listenerDone := make(chan struct{})
+	defer func() {
+		stop()
+		<-listenerDone
+	}()
+	go func() {
+		defer close(listenerDone)
+		for ctx.Err() == nil {
+			err := isaacListener.Run(ctx)
+			if err != nil && ctx.Err() == nil {
+				zap.L().Error("Isaac Service Error", zap.Error(err))
+				return
+			}
+		}
+	}()
+
+	coyoteGame := game.NewGame(&configM.GetConfig().Game, isaacListener)
+	if debugAddr := configM.GetConfig().DebugAddr; debugAddr != "" {
+		stopDebug, err := runDebugServer(debugAddr, coyoteGame.DebugHandler())
+		if err != nil {
+			zap.L().Error("启动调试接口失败", zap.Error(err))
+			return
+		}
+		defer stopDebug()
+	}
+	var devices *deviceManager
+	if configM.GetConfig().Coyote.Mode == model.CLIENT {
+		if coyoteConfig.RelayURL == "" {
+			zap.L().Error("客户端模式需要填写 relay_url")
+			return
+		}
+		zap.L().Info("以客户端模式运行", zap.String("relay", coyoteConfig.RelayURL))
+		// clients are closed after the game so the final zero strength still reaches the app
+		devices = newClientDeviceManager(context.Background(), &coyoteConfig, coyoteGame)
+		defer devices.close()
+	} else {
+		c, stopCoyote, err := runEmbeddedCoyote(&coyoteConfig)
+		if err != nil {
+			zap.L().Error("启动 Coyote 失败", zap.Error(err))
+			return
+		}
+		defer stopCoyote()
+		devices = newEmbeddedDeviceManager(c, coyoteGame)
+	}
+	devices.sync(configM.GetConfig().Devices)
+	configM.RegReloadHandler(func(m *config.Manager) error {
+		devices.sync(m.GetConfig().Devices)
+		return nil
+	})
+	configM.RegReloadHandler(func(m *config.Manager) error {
+		return coyoteGame.Reload()
+	})
+
+	err = coyoteGame.Run(ctx)
+	if err != nil {
+		zap.L().Error("Game Service Error", zap.Error(err))
+		return
 	}
-	if configM.GetConfig().Coyote.Address == "" {
+}
+
+// runEmbeddedCoyote starts the embedded DG-LAB socket server, the returned
+// stop func blocks until it is shut down.
+func runEmbeddedCoyote(coyoteConfig *coyote.Config) (*coyote.Coyote, func(), error) {
+	if coyoteConfig.Address == "" {
 		localAddressList, err := util.GetLocalIP()
 		if err != nil || len(localAddressList) == 0 {
 			zap.L().Error("获取IP失败, 请手动填写ip", zap.Error(err))
-			return
+			return nil, nil, err
 		}
 		if len(localAddressList) > 1 {
 			zap.L().Error("或许你有多个地址(已在下方列出), 请手动在配置文件中指定 ip (一般是192.168.x.x)")
 			zap.L().Info("ip", zap.Any("ips", localAddressList))
-			return
+			return nil, nil, fmt.Errorf("multiple local addresses")
 		}
 
 		coyoteConfig.Address = localAddressList[0]
 	}
-	c := coyote.NewCoyote(&coyoteConfig)
+	c := coyote.NewCoyote(coyoteConfig)
+	// coyote is stopped after the game so the final zero strength still reaches the app
+	coyoteCtx, stopCoyote := context.WithCancel(context.Background())
+	coyoteDone := make(chan struct{})
 	go func() {
-		err = c.Run()
+		defer close(coyoteDone)
+		err := c.Run(coyoteCtx)
 		if err != nil {
 			zap.L().Panic("Coyote Service Error", zap.Error(err))
 			return
 		}
 	}()
 
-	coyoteSession := c.NewSession()
-	_ = util.PrintTerminalQRCode(coyoteSession.GetQRCodeContent())
-	err = util.ShowQRCode("qrcode.png", coyoteSession.GetQRCodeContent())
-	if err != nil {
-		zap.L().Error("获取二维码失败", zap.Error(err))
-		return
-	}
-
-	zap.L().Info("等待连接...... 请使用使用 DG-LAB app 扫码二维码")
-	coyoteSession.RegisterCallback(enums.OnSessionBind, func(session *coyote.Session, callbackData coyote.CallbackData[any]) {
-		zap.L().Info("DG-LAB 已连接")
-	})
-	coyoteSession.WaitForBind()
-
-	isaacListener := isaac.NewGameListener()
-	go func() {
-		for {
-			err = isaacListener.Run()
-			if err != nil {
-				zap.L().Error("Isaac Service Error", zap.Error(err))
-				return
-			}
-		}
-	}()
-
-	coyoteGame := game.NewGame(&configM.GetConfig().Game, coyoteSession, isaacListener)
-	err = coyoteGame.Run()
-	if err != nil {
-		zap.L().Error("Game Service Error", zap.Error(err))
-		return
-	}
+	return c, func() {
+		stopCoyote()
+		<-coyoteDone
+	}, nil
 }
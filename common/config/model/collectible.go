@@ -0,0 +1,33 @@
+package model
+
+// CollectibleModifier applies to every collectible matching all of its set filters.
+// The strength_config bonus of a matching item is multiplied by BonusMultiplier and
+// StrengthAddA/B are added on top, both once per copy of the item. The base strength
+// of the player is not multiplied.
+type CollectibleModifier struct {
+	// Name is only used in logs and the debug endpoint
+	Name string `yaml:"name"`
+
+	Tag  string `yaml:"tag"`
+	Item int    `yaml:"item"`
+	// ItemName matches modded items, their IDs change between runs
+	ItemName string `yaml:"item_name"`
+	// Pool needs the item pools imported from itempools.xml
+	Pool string `yaml:"pool"`
+
+	BonusMultiplier float64 `yaml:"bonus_multiplier"`
+	StrengthAddA    int     `yaml:"strength_add_A"`
+	StrengthAddB    int     `yaml:"strength_add_B"`
+}
+
+func (m *CollectibleModifier) UnmarshalYAML(unmarshal func(interface{}) error) error {
+	type rawCollectibleModifier CollectibleModifier
+	raw := rawCollectibleModifier{
+		BonusMultiplier: 1,
+	}
+	if err := unmarshal(&raw); err != nil {
+		return err
+	}
+	*m = CollectibleModifier(raw)
+	return nil
+}
@@ -1,6 +1,32 @@
 package model
 
+type CoyoteMode string
+
+const (
+	EMBEDDED CoyoteMode = "EMBEDDED"
+	RELAY    CoyoteMode = "RELAY"
+	CLIENT   CoyoteMode = "CLIENT"
+)
+
 type Coyote struct {
-	Address string `yaml:"address"`
-	Port    int    `yaml:"port"`
+	Mode    CoyoteMode `yaml:"mode"`
+	Address string     `yaml:"address"`
+	Port    int        `yaml:"port"`
+
+	TLS CoyoteTLS `yaml:"tls"`
+
+	HeartbeatInterval int `yaml:"heartbeat_interval"`
+	StaleTimeout      int `yaml:"stale_timeout"`
+	EvictTimeout      int `yaml:"evict_timeout"`
+	ResumeGrace       int `yaml:"resume_grace"`
+
+	RelayURL          string `yaml:"relay_url"`
+	ReconnectMinDelay int    `yaml:"reconnect_min_delay"`
+	ReconnectMaxDelay int    `yaml:"reconnect_max_delay"`
+}
+
+type CoyoteTLS struct {
+	Enabled  bool   `yaml:"enabled"`
+	CertFile string `yaml:"cert_file"`
+	KeyFile  string `yaml:"key_file"`
 }
@@ -0,0 +1,25 @@
+package model
+
+// Device is the per-app profile, the game strength of each channel is
+// scaled by Scale and shifted by Offset before it is sent to this device.
+type Device struct {
+	Name string `yaml:"name"`
+
+	ScaleA  float64 `yaml:"scale_A"`
+	ScaleB  float64 `yaml:"scale_B"`
+	OffsetA int     `yaml:"offset_A"`
+	OffsetB int     `yaml:"offset_B"`
+}
+
+func (d *Device) UnmarshalYAML(unmarshal func(interface{}) error) error {
+	type rawDevice Device
+	raw := rawDevice{
+		ScaleA: 1,
+		ScaleB: 1,
+	}
+	if err := unmarshal(&raw); err != nil {
+		return err
+	}
+	*d = Device(raw)
+	return nil
+}
@@ -0,0 +1,36 @@
+package model
+
+import (
+	"IsaacCoyote/common/expr"
+	"fmt"
+	"gopkg.in/yaml.v3"
+)
+
+// Expression is a strength formula such as "base + 3 * lostHearts",
+// a plain number is an expression too. It is nil when left empty.
+type Expression struct {
+	*expr.Expr
+}
+
+func (e *Expression) UnmarshalYAML(value *yaml.Node) error {
+	var src string
+	if err := value.Decode(&src); err != nil {
+		return err
+	}
+	if src == "" {
+		e.Expr = nil
+		return nil
+	}
+
+	compiled, err := expr.Compile(src)
+	if err != nil {
+		return fmt.Errorf("line %d: %w", value.Line, err)
+	}
+	e.Expr = compiled
+	return nil
+}
+
+// NumberExpression is an expression that always evaluates to value.
+func NumberExpression(value int) Expression {
+	return Expression{expr.Number(float64(value))}
+}
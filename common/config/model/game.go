@@ -44,12 +44,22 @@ type Game struct {
 	BaseStrengthB      int `yaml:"base_strength_B"`
 	StrengthPerHealthA int `yaml:"strength_per_health_A"`
 	StrengthPerHealthB int `yaml:"strength_per_health_B"`
+	// StrengthFormulaA and StrengthFormulaB replace the minimum strength
+	// base + perHealth * lostHealth + collectibles when set
+	StrengthFormulaA Expression `yaml:"strength_formula_A"`
+	StrengthFormulaB Expression `yaml:"strength_formula_B"`
 
 	ContinuousMode   ContinuousMode   `yaml:"continuous_mode"`
 	OnNewCollectible OnNewCollectible `yaml:"on_new_collectible"`
-	OnHurt           OnHurt           `yaml:"on_hurt"`
-	OnDeath          OnDeath          `yaml:"on_death"`
-	OnManualRestart  OnManualRestart  `yaml:"on_manual_restart"`
+	Rules            []Rule           `yaml:"rules"`
+	Scheduler        Scheduler        `yaml:"scheduler"`
+	// Players routes each local co-op player, only player 1 drives every channel when empty
+	Players []PlayerRoute `yaml:"players"`
+
+	// Deprecated: use Rules
+	OnHurt          Stimulus `yaml:"on_hurt"`
+	OnDeath         Stimulus `yaml:"on_death"`
+	OnManualRestart Stimulus `yaml:"on_manual_restart"`
 }
 
 type ContinuousMode struct {
@@ -63,14 +73,20 @@ type ContinuousMode struct {
 }
 
 type OnNewCollectible struct {
-	Enabled        bool `yaml:"enabled"`
+	Enabled bool `yaml:"enabled"`
+	// DefaultQuality is the quality of items missing from the resources, e.g. modded ones
+	DefaultQuality int `yaml:"default_quality"`
 	StrengthConfig map[int]struct {
 		StrengthAddA int `yaml:"strength_add_A"`
 		StrengthAddB int `yaml:"strength_add_B"`
 	} `yaml:"strength_config"`
+	// Modifiers adjust single items on top of StrengthConfig, in order
+	Modifiers []CollectibleModifier `yaml:"modifiers"`
 }
 
-type OnHurt struct {
+// Stimulus is the deprecated on_hurt / on_death / on_manual_restart section,
+// it is translated into an equivalent Rule.
+type Stimulus struct {
 	Enabled bool `yaml:"enabled"`
 
 	Duration int `yaml:"duration"`
@@ -83,27 +99,38 @@ type OnHurt struct {
 	PulseB PulseConfig `yaml:"pulse_B"`
 }
 
-type OnDeath struct {
-	Enabled bool `yaml:"enabled"`
-
-	Duration int `yaml:"duration"`
-
-	StrengthOperator StrengthOperator `yaml:"strength_operator"`
-	StrengthA        int              `yaml:"strength_A"`
-	StrengthB        int              `yaml:"strength_B"`
-
-	PulseA PulseConfig `yaml:"pulse_A"`
-	PulseB PulseConfig `yaml:"pulse_B"`
-}
-
-type OnManualRestart struct {
-	Enabled  bool `yaml:"enabled"`
-	Duration int  `yaml:"duration"`
-
-	StrengthOperator StrengthOperator `yaml:"strength_operator"`
-	StrengthA        int              `yaml:"strength_A"`
-	StrengthB        int              `yaml:"strength_B"`
+// LegacyRules translates the deprecated sections that are enabled.
+func (g *Game) LegacyRules() []Rule {
+	legacy := []struct {
+		name     string
+		event    string
+		lane     Lane
+		queue    QueuePolicy
+		stimulus Stimulus
+	}{
+		{"on_hurt", "PlayerHurtEvent", LANE_EVENT, PREEMPT, g.OnHurt},
+		{"on_death", "PlayerDeathEvent", LANE_CRITICAL, REPLACE_ALL, g.OnDeath},
+		{"on_manual_restart", "ManualRestartEvent", LANE_CRITICAL, REPLACE_ALL, g.OnManualRestart},
+	}
 
-	PulseA PulseConfig `yaml:"pulse_A"`
-	PulseB PulseConfig `yaml:"pulse_B"`
+	var rules []Rule
+	for _, l := range legacy {
+		if !l.stimulus.Enabled {
+			continue
+		}
+		rules = append(rules, Rule{
+			Name:             l.name,
+			Event:            l.event,
+			Enabled:          true,
+			Duration:         NumberExpression(l.stimulus.Duration),
+			StrengthOperator: l.stimulus.StrengthOperator,
+			StrengthA:        NumberExpression(l.stimulus.StrengthA),
+			StrengthB:        NumberExpression(l.stimulus.StrengthB),
+			PulseA:           l.stimulus.PulseA,
+			PulseB:           l.stimulus.PulseB,
+			Lane:             l.lane,
+			Queue:            l.queue,
+		})
+	}
+	return rules
 }
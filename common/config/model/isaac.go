@@ -0,0 +1,41 @@
+package model
+
+type IsaacTransport string
+
+const (
+	FILE   IsaacTransport = "FILE"
+	SOCKET IsaacTransport = "SOCKET"
+)
+
+// Isaac configures how the listener reaches the mod. For the FILE transport the
+// first non-empty of SaveFile, DataDir and GameDir is used, the game process is
+// only looked up by ProcessName when all of them are empty.
+type Isaac struct {
+	// Transport is FILE (default) or SOCKET, which needs Isaac started with --luadebug
+	Transport  IsaacTransport `yaml:"transport"`
+	SocketPort int            `yaml:"socket_port"`
+
+	ProcessName string `yaml:"process_name"`
+	GameDir     string `yaml:"game_dir"`
+	DataDir     string `yaml:"data_dir"`
+	SaveFile    string `yaml:"save_file"`
+	// SaveSlot picks saveN.dat, 0 uses the most recently written one
+	SaveSlot int `yaml:"save_slot"`
+	// Polling reads the data file periodically instead of watching it for changes
+	Polling bool `yaml:"polling"`
+
+	Resources Resources `yaml:"resources"`
+}
+
+// Resources overrides the item database compiled into the binary. Entries are
+// merged by ID in the order Dir, ItemsXML, ItemPoolsXML, PocketItemsXML, ModDirs.
+type Resources struct {
+	// Dir holds collectibles.json, trinkets.json, cards.json or pills.json in the embedded format
+	Dir string `yaml:"dir"`
+	// ItemsXML, ItemPoolsXML and PocketItemsXML are the game's own resource files
+	ItemsXML       string `yaml:"items_xml"`
+	ItemPoolsXML   string `yaml:"itempools_xml"`
+	PocketItemsXML string `yaml:"pocketitems_xml"`
+	// ModDirs are mod folders whose content/items.xml is imported, modded items are matched by name
+	ModDirs []string `yaml:"mod_dirs"`
+}
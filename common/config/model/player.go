@@ -0,0 +1,48 @@
+package model
+
+import "IsaacCoyote/pkg/coyote/enums"
+
+// RouteChannel is the channel a co-op player drives.
+type RouteChannel string
+
+const (
+	ROUTE_A    RouteChannel = "A"
+	ROUTE_B    RouteChannel = "B"
+	ROUTE_BOTH RouteChannel = "BOTH"
+)
+
+// PlayerRoute sends the stimulus of one local co-op player to a channel and device.
+type PlayerRoute struct {
+	// Player is 1 for player 1, as shown in game
+	Player  int          `yaml:"player"`
+	Channel RouteChannel `yaml:"channel"`
+	// Device is the name of a device in devices, empty for every device
+	Device string `yaml:"device"`
+}
+
+func (r *PlayerRoute) UnmarshalYAML(unmarshal func(interface{}) error) error {
+	type rawPlayerRoute PlayerRoute
+	raw := rawPlayerRoute{
+		Player:  1,
+		Channel: ROUTE_BOTH,
+	}
+	if err := unmarshal(&raw); err != nil {
+		return err
+	}
+	*r = PlayerRoute(raw)
+	return nil
+}
+
+// Drives reports whether the route drives channel of the device called deviceName.
+func (r PlayerRoute) Drives(deviceName string, channel enums.ChannelType) bool {
+	if r.Device != "" && r.Device != deviceName {
+		return false
+	}
+	switch r.Channel {
+	case ROUTE_A:
+		return channel == enums.ChannelTypeA
+	case ROUTE_B:
+		return channel == enums.ChannelTypeB
+	}
+	return true
+}
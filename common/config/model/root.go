@@ -4,7 +4,11 @@ type ConfigRoot struct {
 	Name    string `yaml:"name"`
 	Version string `yaml:"version"`
 	Debug   bool   `yaml:"debug"`
+	// DebugAddr serves the debug endpoints, e.g. 127.0.0.1:8802, empty to disable
+	DebugAddr string `yaml:"debug_addr"`
 
-	Coyote Coyote `yaml:"coyote"`
-	Game   Game   `yaml:"game"`
+	Coyote  Coyote   `yaml:"coyote"`
+	Devices []Device `yaml:"devices"`
+	Isaac   Isaac    `yaml:"isaac"`
+	Game    Game     `yaml:"game"`
 }
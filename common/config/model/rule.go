@@ -0,0 +1,57 @@
+package model
+
+// QueuePolicy decides where the pulses of a rule go in the queue of its lane.
+type QueuePolicy string
+
+const (
+	APPEND  QueuePolicy = "APPEND"  // after everything already queued
+	PREEMPT QueuePolicy = "PREEMPT" // in front of the queue, the rest plays afterwards
+	REPLACE QueuePolicy = "REPLACE" // the queue of the lane is cleared first
+	CLEAR   QueuePolicy = "CLEAR"   // only clears the queue of the lane, no pulses are added
+
+	REPLACE_ALL QueuePolicy = "REPLACE_ALL" // the queues of every lane are cleared first
+	CLEAR_ALL   QueuePolicy = "CLEAR_ALL"   // only clears the queues of every lane, no pulses are added
+)
+
+// Rule maps a game event to a stimulus.
+type Rule struct {
+	Name    string `yaml:"name"`
+	Event   string `yaml:"event"`
+	Enabled bool   `yaml:"enabled"`
+	// Conditions on the event payload that all have to hold, e.g. "damage >= 2"
+	// or "flags & DAMAGE_EXPLOSION"
+	Conditions []string `yaml:"conditions"`
+
+	// Duration in milliseconds, it may use the same variables as StrengthA
+	Duration Expression `yaml:"duration"`
+
+	StrengthOperator StrengthOperator `yaml:"strength_operator"`
+	// StrengthA and StrengthB may use the variables of the event payload
+	StrengthA Expression `yaml:"strength_A"`
+	StrengthB Expression `yaml:"strength_B"`
+
+	PulseA PulseConfig `yaml:"pulse_A"`
+	PulseB PulseConfig `yaml:"pulse_B"`
+
+	// Lane is EVENT or CRITICAL
+	Lane  Lane        `yaml:"lane"`
+	Queue QueuePolicy `yaml:"queue"`
+	// Final skips the later rules of the event once this rule matched,
+	// so specific rules placed first override the generic ones
+	Final bool `yaml:"final"`
+}
+
+func (r *Rule) UnmarshalYAML(unmarshal func(interface{}) error) error {
+	type rawRule Rule
+	raw := rawRule{
+		Enabled:          true,
+		StrengthOperator: INCREMENT,
+		Lane:             LANE_EVENT,
+		Queue:            PREEMPT,
+	}
+	if err := unmarshal(&raw); err != nil {
+		return err
+	}
+	*r = Rule(raw)
+	return nil
+}
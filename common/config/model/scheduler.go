@@ -0,0 +1,46 @@
+package model
+
+// Lane is a priority lane of the stimulus scheduler, from low to high priority
+// AMBIENT, EVENT and CRITICAL.
+type Lane string
+
+const (
+	LANE_AMBIENT  Lane = "AMBIENT" // continuous mode, rules cannot use it
+	LANE_EVENT    Lane = "EVENT"
+	LANE_CRITICAL Lane = "CRITICAL"
+)
+
+// MergePolicy combines a lane with the lanes below it that are still playing.
+type MergePolicy string
+
+const (
+	MERGE_MAX     MergePolicy = "MAX"     // the stronger slot plays
+	MERGE_SUM     MergePolicy = "SUM"     // the strengths add up, the waveform of the lane plays
+	MERGE_REPLACE MergePolicy = "REPLACE" // only the lane plays
+)
+
+type LaneConfig struct {
+	Merge MergePolicy `yaml:"merge"`
+	// Preempt pauses the lanes below while this lane has pulses queued,
+	// they resume where they stopped afterwards
+	Preempt bool `yaml:"preempt"`
+}
+
+func (l *LaneConfig) UnmarshalYAML(unmarshal func(interface{}) error) error {
+	type rawLaneConfig LaneConfig
+	raw := rawLaneConfig{
+		Merge:   MERGE_MAX,
+		Preempt: true,
+	}
+	if err := unmarshal(&raw); err != nil {
+		return err
+	}
+	*l = LaneConfig(raw)
+	return nil
+}
+
+// Scheduler configures the lanes above AMBIENT, a lane left out merges with MAX and preempts.
+type Scheduler struct {
+	Event    LaneConfig `yaml:"event"`
+	Critical LaneConfig `yaml:"critical"`
+}
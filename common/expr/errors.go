@@ -0,0 +1,33 @@
+package expr
+
+import "fmt"
+
+// SyntaxError is returned by Compile, Pos is the 1-based position in the source.
+type SyntaxError struct {
+	Message string
+	Source  string
+	Pos     int
+}
+
+func (e SyntaxError) Error() string {
+	return fmt.Sprintf("position %d in %q: %s", e.Pos, e.Source, e.Message)
+}
+
+// UndefinedVariableError is returned for variables that are not in the variable set.
+type UndefinedVariableError struct {
+	Message string
+	Source  string
+	Pos     int
+}
+
+func (e UndefinedVariableError) Error() string {
+	return fmt.Sprintf("position %d in %q: %s", e.Pos, e.Source, e.Message)
+}
+
+type EvalError struct {
+	Message string
+}
+
+func (e EvalError) Error() string {
+	return e.Message
+}
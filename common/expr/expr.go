@@ -0,0 +1,350 @@
+// Package expr evaluates the arithmetic expressions of the config, e.g.
+// "base + 3 * lostHearts + clamp(damage * 10, 0, 30)".
+//
+// Expressions support numbers, variables, + - * / %, parentheses and the
+// functions min, max, clamp, abs and round.
+package expr
+
+import (
+	"fmt"
+	"math"
+	"slices"
+	"strconv"
+)
+
+type Expr struct {
+	src  string
+	root node
+}
+
+// Compile parses src, syntax errors carry the position they occurred at.
+func Compile(src string) (*Expr, error) {
+	tokens, err := tokenize(src)
+	if err != nil {
+		return nil, err
+	}
+
+	p := &parser{src: src, tokens: tokens}
+	root, err := p.parseExpr()
+	if err != nil {
+		return nil, err
+	}
+	if t := p.peek(); t.kind != tokenEOF {
+		return nil, p.errorf(t, "unexpected %s", t)
+	}
+	return &Expr{src: src, root: root}, nil
+}
+
+// MustCompile is Compile for expressions known to be valid, it panics on error.
+func MustCompile(src string) *Expr {
+	e, err := Compile(src)
+	if err != nil {
+		panic(err)
+	}
+	return e
+}
+
+// Number is an expression that always evaluates to value.
+func Number(value float64) *Expr {
+	return &Expr{
+		src:  strconv.FormatFloat(value, 'f', -1, 64),
+		root: numberNode{value: value},
+	}
+}
+
+func (e *Expr) String() string {
+	return e.src
+}
+
+// Check makes sure every variable of the expression is defined.
+func (e *Expr) Check(isDefined func(name string) bool) error {
+	var err error
+	e.root.walk(func(n node) {
+		if v, ok := n.(varNode); ok && err == nil && !isDefined(v.name) {
+			err = UndefinedVariableError{
+				Message: fmt.Sprintf("undefined variable %q", v.name),
+				Source:  e.src,
+				Pos:     v.pos,
+			}
+		}
+	})
+	return err
+}
+
+// Eval evaluates the expression, variables missing from vars are an error.
+func (e *Expr) Eval(vars map[string]float64) (float64, error) {
+	return e.root.eval(vars)
+}
+
+type node interface {
+	eval(vars map[string]float64) (float64, error)
+	walk(fn func(node))
+}
+
+type numberNode struct {
+	value float64
+}
+
+func (n numberNode) eval(map[string]float64) (float64, error) {
+	return n.value, nil
+}
+
+func (n numberNode) walk(fn func(node)) {
+	fn(n)
+}
+
+type varNode struct {
+	name string
+	pos  int
+}
+
+func (n varNode) eval(vars map[string]float64) (float64, error) {
+	value, ok := vars[n.name]
+	if !ok {
+		return 0, EvalError{
+			Message: fmt.Sprintf("undefined variable %q", n.name),
+		}
+	}
+	return value, nil
+}
+
+func (n varNode) walk(fn func(node)) {
+	fn(n)
+}
+
+type negNode struct {
+	operand node
+}
+
+func (n negNode) eval(vars map[string]float64) (float64, error) {
+	value, err := n.operand.eval(vars)
+	return -value, err
+}
+
+func (n negNode) walk(fn func(node)) {
+	fn(n)
+	n.operand.walk(fn)
+}
+
+type binaryNode struct {
+	op          string
+	left, right node
+}
+
+func (n binaryNode) eval(vars map[string]float64) (float64, error) {
+	left, err := n.left.eval(vars)
+	if err != nil {
+		return 0, err
+	}
+	right, err := n.right.eval(vars)
+	if err != nil {
+		return 0, err
+	}
+
+	switch n.op {
+	case "+":
+		return left + right, nil
+	case "-":
+		return left - right, nil
+	case "*":
+		return left * right, nil
+	case "/", "%":
+		if right == 0 {
+			return 0, EvalError{
+				Message: "division by zero",
+			}
+		}
+		if n.op == "%" {
+			return math.Mod(left, right), nil
+		}
+		return left / right, nil
+	}
+	return 0, EvalError{
+		Message: "unknown operator " + n.op,
+	}
+}
+
+func (n binaryNode) walk(fn func(node)) {
+	fn(n)
+	n.left.walk(fn)
+	n.right.walk(fn)
+}
+
+type function struct {
+	minArgs, maxArgs int // maxArgs < 0 means variadic
+	call             func(args []float64) float64
+}
+
+var functions = map[string]function{
+	"min": {1, -1, func(args []float64) float64 {
+		return slices.Min(args)
+	}},
+	"max": {1, -1, func(args []float64) float64 {
+		return slices.Max(args)
+	}},
+	"clamp": {3, 3, func(args []float64) float64 {
+		return math.Min(math.Max(args[0], args[1]), args[2])
+	}},
+	"abs": {1, 1, func(args []float64) float64 {
+		return math.Abs(args[0])
+	}},
+	"round": {1, 1, func(args []float64) float64 {
+		return math.Round(args[0])
+	}},
+}
+
+type callNode struct {
+	fn   function
+	args []node
+}
+
+func (n callNode) eval(vars map[string]float64) (float64, error) {
+	args := make([]float64, len(n.args))
+	for i, arg := range n.args {
+		value, err := arg.eval(vars)
+		if err != nil {
+			return 0, err
+		}
+		args[i] = value
+	}
+	return n.fn.call(args), nil
+}
+
+func (n callNode) walk(fn func(node)) {
+	fn(n)
+	for _, arg := range n.args {
+		arg.walk(fn)
+	}
+}
+
+type parser struct {
+	src    string
+	tokens []token
+	index  int
+}
+
+func (p *parser) peek() token {
+	return p.tokens[p.index]
+}
+
+func (p *parser) next() token {
+	t := p.tokens[p.index]
+	if t.kind != tokenEOF {
+		p.index++
+	}
+	return t
+}
+
+func (p *parser) errorf(t token, format string, args ...any) error {
+	return SyntaxError{
+		Message: fmt.Sprintf(format, args...),
+		Source:  p.src,
+		Pos:     t.pos,
+	}
+}
+
+// parseExpr parses a sum, which is the lowest precedence level.
+func (p *parser) parseExpr() (node, error) {
+	left, err := p.parseTerm()
+	if err != nil {
+		return nil, err
+	}
+	for t := p.peek(); t.kind == tokenOperator && (t.text == "+" || t.text == "-"); t = p.peek() {
+		p.next()
+		right, err := p.parseTerm()
+		if err != nil {
+			return nil, err
+		}
+		left = binaryNode{op: t.text, left: left, right: right}
+	}
+	return left, nil
+}
+
+func (p *parser) parseTerm() (node, error) {
+	left, err := p.parseUnary()
+	if err != nil {
+		return nil, err
+	}
+	for t := p.peek(); t.kind == tokenOperator && (t.text == "*" || t.text == "/" || t.text == "%"); t = p.peek() {
+		p.next()
+		right, err := p.parseUnary()
+		if err != nil {
+			return nil, err
+		}
+		left = binaryNode{op: t.text, left: left, right: right}
+	}
+	return left, nil
+}
+
+func (p *parser) parseUnary() (node, error) {
+	t := p.peek()
+	if t.kind == tokenOperator && (t.text == "-" || t.text == "+") {
+		p.next()
+		operand, err := p.parseUnary()
+		if err != nil {
+			return nil, err
+		}
+		if t.text == "-" {
+			return negNode{operand: operand}, nil
+		}
+		return operand, nil
+	}
+	return p.parsePrimary()
+}
+
+func (p *parser) parsePrimary() (node, error) {
+	t := p.next()
+	switch t.kind {
+	case tokenNumber:
+		return numberNode{value: t.number}, nil
+	case tokenLParen:
+		inner, err := p.parseExpr()
+		if err != nil {
+			return nil, err
+		}
+		if closing := p.next(); closing.kind != tokenRParen {
+			return nil, p.errorf(closing, "expected \")\", got %s", closing)
+		}
+		return inner, nil
+	case tokenIdent:
+		if p.peek().kind == tokenLParen {
+			return p.parseCall(t)
+		}
+		return varNode{name: t.text, pos: t.pos}, nil
+	}
+	return nil, p.errorf(t, "unexpected %s", t)
+}
+
+func (p *parser) parseCall(name token) (node, error) {
+	fn, ok := functions[name.text]
+	if !ok {
+		return nil, p.errorf(name, "unknown function %q", name.text)
+	}
+	p.next() // (
+
+	var args []node
+	if p.peek().kind == tokenRParen {
+		p.next()
+	} else {
+		for {
+			arg, err := p.parseExpr()
+			if err != nil {
+				return nil, err
+			}
+			args = append(args, arg)
+
+			t := p.next()
+			if t.kind == tokenRParen {
+				break
+			}
+			if t.kind != tokenComma {
+				return nil, p.errorf(t, "expected \",\" or \")\", got %s", t)
+			}
+		}
+	}
+
+	if len(args) < fn.minArgs || (fn.maxArgs >= 0 && len(args) > fn.maxArgs) {
+		return nil, p.errorf(name, "wrong number of arguments for %s: %d", name.text, len(args))
+	}
+	return callNode{fn: fn, args: args}, nil
+}
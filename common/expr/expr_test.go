@@ -0,0 +1,131 @@
+package expr
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestEval(t *testing.T) {
+	vars := map[string]float64{
+		"base":       10,
+		"lostHearts": 2,
+		"floor":      4,
+		"damage":     5,
+	}
+	tests := []struct {
+		src  string
+		want float64
+	}{
+		{"1 + 2 * 3", 7},
+		{"(1 + 2) * 3", 9},
+		{"10 - 4 - 3", 3},
+		{"12 / 3 / 2", 2},
+		{"7 % 4", 3},
+		{"2 + 7 % 4 * 2", 8},
+		{"-2 * -3", 6},
+		{"-(1 + 2)", -3},
+		{"+1.5", 1.5},
+		{"base + 3 * lostHearts + 0.5 * floor + clamp(damage*10, 0, 30)", 48},
+		{"clamp(-5, 0, 30)", 0},
+		{"clamp(15, 0, 30)", 15},
+		{"min(3, 1, 2)", 1},
+		{"max(3, 1, 2)", 3},
+		{"abs(-2.5)", 2.5},
+		{"round(2.5)", 3},
+		{"max(base, floor) - min(damage)", 5},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.src, func(t *testing.T) {
+			e, err := Compile(tt.src)
+			if err != nil {
+				t.Fatal(err)
+			}
+			got, err := e.Eval(vars)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got != tt.want {
+				t.Fatalf("Eval = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestCompileError(t *testing.T) {
+	tests := []struct {
+		src string
+		pos int
+	}{
+		{"base + * 3", 8},
+		{"(base + 1", 10},
+		{"clamp(1, 2)", 1},
+		{"min()", 1},
+		{"abs(1, 2)", 1},
+		{"foo(1)", 1},
+		{"max(1 2)", 7},
+		{"1 2", 3},
+		{"base # 2", 6},
+		{"1..2", 1},
+		{"", 1},
+		{"(1))", 4},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.src, func(t *testing.T) {
+			_, err := Compile(tt.src)
+			var syntaxErr SyntaxError
+			if !errors.As(err, &syntaxErr) {
+				t.Fatalf("Compile error = %v, want SyntaxError", err)
+			}
+			if syntaxErr.Pos != tt.pos {
+				t.Fatalf("Pos = %d, want %d: %v", syntaxErr.Pos, tt.pos, err)
+			}
+		})
+	}
+}
+
+func TestCheck(t *testing.T) {
+	isDefined := func(name string) bool {
+		return name == "base" || name == "lostHearts"
+	}
+	tests := []struct {
+		src string
+		pos int // 0 when every variable is defined
+	}{
+		{"base + lostHearts", 0},
+		{"base + lostHeart", 8},
+		{"max(base, 2 * floor)", 15},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.src, func(t *testing.T) {
+			err := MustCompile(tt.src).Check(isDefined)
+			if tt.pos == 0 {
+				if err != nil {
+					t.Fatal(err)
+				}
+				return
+			}
+			var undefinedErr UndefinedVariableError
+			if !errors.As(err, &undefinedErr) {
+				t.Fatalf("Check error = %v, want UndefinedVariableError", err)
+			}
+			if undefinedErr.Pos != tt.pos {
+				t.Fatalf("Pos = %d, want %d: %v", undefinedErr.Pos, tt.pos, err)
+			}
+		})
+	}
+}
+
+func TestEvalError(t *testing.T) {
+	for _, src := range []string{"1 / 0", "5 % (2 - 2)", "base + 1"} {
+		t.Run(src, func(t *testing.T) {
+			_, err := MustCompile(src).Eval(map[string]float64{})
+			var evalErr EvalError
+			if !errors.As(err, &evalErr) {
+				t.Fatalf("Eval error = %v, want EvalError", err)
+			}
+		})
+	}
+}
@@ -0,0 +1,123 @@
+package game
+
+import (
+	configModel "IsaacCoyote/common/config/model"
+	"fmt"
+	"go.uber.org/zap"
+	"math"
+	"slices"
+)
+
+// collectibleBonus explains the strength one collectible adds, for all of its copies.
+type collectibleBonus struct {
+	ID      int    `json:"id"`
+	Name    string `json:"name"`
+	Num     int    `json:"num"`
+	Quality int    `json:"quality"`
+
+	// QualityA/B are the strength_config bonus of one copy
+	QualityA        int      `json:"qualityA"`
+	QualityB        int      `json:"qualityB"`
+	BonusMultiplier float64  `json:"bonusMultiplier"`
+	AddA            int      `json:"addA"`
+	AddB            int      `json:"addB"`
+	Modifiers       []string `json:"modifiers,omitempty"`
+
+	StrengthA float64 `json:"strengthA"`
+	StrengthB float64 `json:"strengthB"`
+}
+
+func matchModifier(m configModel.CollectibleModifier, item itemDetailWrapper) bool {
+	detail := item.itemDetail
+	if m.Tag != "" && !slices.Contains(detail.Tags, m.Tag) {
+		return false
+	}
+	if m.Item != 0 && m.Item != detail.ID {
+		return false
+	}
+	if m.ItemName != "" && m.ItemName != detail.Name {
+		return false
+	}
+	if m.Pool != "" && !slices.Contains(detail.Pools, m.Pool) {
+		return false
+	}
+	return true
+}
+
+func checkModifiers(modifiers []configModel.CollectibleModifier) error {
+	for i, m := range modifiers {
+		var err string
+		if m.Tag == "" && m.Item == 0 && m.ItemName == "" && m.Pool == "" {
+			err = "one of tag, item, item_name or pool is required"
+		} else if m.BonusMultiplier < 0 {
+			err = fmt.Sprintf("bonus_multiplier %v is negative", m.BonusMultiplier)
+		}
+		if err != "" {
+			return InvalidModifierError{
+				Message: fmt.Sprintf("modifier %d (%s): %s", i, m.Name, err),
+			}
+		}
+	}
+	return nil
+}
+
+// collectibleBonuses rates every collectible with strength_config and the modifiers.
+func (g *Game) collectibleBonuses(collectibles []itemDetailWrapper) []collectibleBonus {
+	config := g.config.OnNewCollectible
+	bonuses := make([]collectibleBonus, 0, len(collectibles))
+	for _, item := range collectibles {
+		bonus := collectibleBonus{
+			ID:              item.itemDetail.ID,
+			Name:            item.itemDetail.Name,
+			Num:             item.num,
+			Quality:         item.itemDetail.Quality,
+			BonusMultiplier: 1,
+		}
+		if quality := item.itemDetail.Quality; quality >= 0 {
+			if strengthConfig, ok := config.StrengthConfig[quality]; ok {
+				bonus.QualityA = strengthConfig.StrengthAddA
+				bonus.QualityB = strengthConfig.StrengthAddB
+			} else {
+				zap.L().Info("未配置的强度: " + item.itemDetail.Name)
+			}
+		}
+
+		for i, m := range config.Modifiers {
+			if !matchModifier(m, item) {
+				continue
+			}
+			bonus.BonusMultiplier *= m.BonusMultiplier
+			bonus.AddA += m.StrengthAddA
+			bonus.AddB += m.StrengthAddB
+			name := m.Name
+			if name == "" {
+				name = fmt.Sprintf("#%d", i)
+			}
+			bonus.Modifiers = append(bonus.Modifiers, name)
+		}
+
+		bonus.StrengthA = (float64(bonus.QualityA)*bonus.BonusMultiplier + float64(bonus.AddA)) * float64(item.num)
+		bonus.StrengthB = (float64(bonus.QualityB)*bonus.BonusMultiplier + float64(bonus.AddB)) * float64(item.num)
+		bonuses = append(bonuses, bonus)
+	}
+	return bonuses
+}
+
+// updateCollectibleStrength recomputes the collectible strength of the player.
+func (g *Game) updateCollectibleStrength(playerIndex int, info *playerInfo) {
+	info.collectibleBonuses = nil
+	info.collStrengthAddA, info.collStrengthAddB = 0, 0
+	if !g.config.OnNewCollectible.Enabled {
+		return
+	}
+
+	info.collectibleBonuses = g.collectibleBonuses(info.Collectibles)
+	var strengthA, strengthB float64
+	for _, bonus := range info.collectibleBonuses {
+		strengthA += bonus.StrengthA
+		strengthB += bonus.StrengthB
+	}
+	info.collStrengthAddA = int(math.Round(strengthA))
+	info.collStrengthAddB = int(math.Round(strengthB))
+	zap.L().Debug("更新物品强度", zap.Int("player", playerIndex+1), zap.Any("strengthA", info.collStrengthAddA), zap.Any("strengthB", info.collStrengthAddB))
+}
@@ -0,0 +1,104 @@
+package game
+
+import (
+	configModel "IsaacCoyote/common/config/model"
+	"encoding/json"
+	"go.uber.org/zap"
+	"maps"
+	"net/http"
+	"slices"
+)
+
+type collectibleDebugInfo struct {
+	// Player is 1 for player 1
+	Player    int                `json:"player"`
+	StrengthA int                `json:"strengthA"`
+	StrengthB int                `json:"strengthB"`
+	Items     []collectibleBonus `json:"items"`
+}
+
+type channelDebugInfo struct {
+	// Queued is how many milliseconds each lane has queued
+	Queued   map[configModel.Lane]int `json:"queued"`
+	Upcoming []timelineEntry          `json:"upcoming"`
+}
+
+type trackDebugInfo struct {
+	Player  int                      `json:"player"`
+	Channel configModel.RouteChannel `json:"channel"`
+	Device  string                   `json:"device,omitempty"`
+	A       channelDebugInfo         `json:"A"`
+	B       channelDebugInfo         `json:"B"`
+}
+
+// previewLimit is how far /debug/timeline looks ahead, 60 seconds
+const previewLimit = 300
+
+// DebugHandler serves the debug endpoints:
+// /debug/collectibles explains the collectible strength of every player,
+// /debug/timeline shows what every track is going to play.
+func (g *Game) DebugHandler() http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /debug/collectibles", func(w http.ResponseWriter, r *http.Request) {
+		writeDebugJSON(w, g.collectibleDebugInfo())
+	})
+	mux.HandleFunc("GET /debug/timeline", func(w http.ResponseWriter, r *http.Request) {
+		writeDebugJSON(w, g.timelineDebugInfo())
+	})
+	return mux
+}
+
+// timelineDebugInfo previews the tracks with the current minimum strength, the
+// actual timeline differs once it changes or new events arrive.
+func (g *Game) timelineDebugInfo() []trackDebugInfo {
+	lanes := g.getLaneSettings()
+
+	g.scheduleLock.Lock()
+	defer g.scheduleLock.Unlock()
+
+	result := make([]trackDebugInfo, 0, len(g.tracks))
+	for _, t := range g.tracks {
+		ambientA, ambientB := g.ambientSettings(t)
+		result = append(result, trackDebugInfo{
+			Player:  t.route.Player,
+			Channel: t.route.Channel,
+			Device:  t.route.Device,
+			A: channelDebugInfo{
+				Queued:   t.timelineA.queuedDuration(),
+				Upcoming: t.timelineA.preview(lanes, ambientA, previewLimit),
+			},
+			B: channelDebugInfo{
+				Queued:   t.timelineB.queuedDuration(),
+				Upcoming: t.timelineB.preview(lanes, ambientB, previewLimit),
+			},
+		})
+	}
+	return result
+}
+
+func (g *Game) collectibleDebugInfo() []collectibleDebugInfo {
+	g.playerLock.Lock()
+	defer g.playerLock.Unlock()
+
+	result := make([]collectibleDebugInfo, 0, len(g.players))
+	for _, playerIndex := range slices.Sorted(maps.Keys(g.players)) {
+		info := g.players[playerIndex]
+		result = append(result, collectibleDebugInfo{
+			Player:    playerIndex + 1,
+			StrengthA: info.collStrengthAddA,
+			StrengthB: info.collStrengthAddB,
+			Items:     slices.Clone(info.collectibleBonuses),
+		})
+	}
+	return result
+}
+
+func writeDebugJSON(w http.ResponseWriter, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+	err := encoder.Encode(v)
+	if err != nil {
+		zap.L().Error("写入调试信息失败", zap.Error(err))
+	}
+}
@@ -0,0 +1,86 @@
+package game
+
+import (
+	configModel "IsaacCoyote/common/config/model"
+	"IsaacCoyote/pkg/coyote/enums"
+	"go.uber.org/zap"
+	"math"
+	"sync/atomic"
+)
+
+// device is one DG-LAB app driven by the game.
+type device struct {
+	name    string
+	session StimController
+	profile configModel.Device
+
+	// isPaused is updated by dispatchPulse and copied by AddDevice when the
+	// device is replaced with the same session
+	isPaused atomic.Bool
+}
+
+// scaleStrength applies the device profile and clamps to the limits reported by the app.
+func (d *device) scaleStrength(strengthA int, strengthB int) (int, int) {
+	limits := d.session.GetStrengthData()
+
+	strengthA = int(math.Round(float64(strengthA)*d.profile.ScaleA)) + d.profile.OffsetA
+	strengthB = int(math.Round(float64(strengthB)*d.profile.ScaleB)) + d.profile.OffsetB
+	strengthA = min(max(strengthA, 0), limits.MaxStrengthA)
+	strengthB = min(max(strengthB, 0), limits.MaxStrengthB)
+	return strengthA, strengthB
+}
+
+func (d *device) sendSegment(segment pulseSegment) {
+	strengthA, strengthB := d.scaleStrength(segment.StrengthA, segment.StrengthB)
+
+	err := d.session.SetStrength(enums.ChannelTypeA, enums.StrengthActionSetTo, strengthA)
+	logSessionError("Failed to set strength A", err)
+	err = d.session.SetStrength(enums.ChannelTypeB, enums.StrengthActionSetTo, strengthB)
+	logSessionError("Failed to set strength B", err)
+
+	err = d.session.AddPulse(enums.ChannelTypeA, segment.FramesA)
+	logSessionError("failed to add pulse", err)
+	err = d.session.AddPulse(enums.ChannelTypeB, segment.FramesB)
+	logSessionError("failed to add pulse", err)
+}
+
+func (d *device) setZeroStrength() {
+	err := d.session.SetStrength(enums.ChannelTypeA, enums.StrengthActionSetTo, 0)
+	logSessionError("Failed to set strength A", err)
+	err = d.session.SetStrength(enums.ChannelTypeB, enums.StrengthActionSetTo, 0)
+	logSessionError("Failed to set strength B", err)
+}
+
+func (d *device) drain() {
+	if !d.session.IsBound() {
+		return
+	}
+	for _, channel := range []enums.ChannelType{enums.ChannelTypeA, enums.ChannelTypeB} {
+		err := d.session.ClearPulse(channel)
+		logSessionError("failed to clear pulse", err)
+		err = d.session.SetStrength(channel, enums.StrengthActionSetTo, 0)
+		logSessionError("Failed to set strength "+channel.String(), err)
+	}
+}
+
+// checkBound pauses this device while its app is disconnected.
+func (d *device) checkBound() bool {
+	isBound := d.session.IsBound()
+	switch {
+	case !isBound && d.isPaused.CompareAndSwap(false, true):
+		zap.L().Warn("DG-LAB 已断开, 暂停发电", zap.String("device", d.name))
+	case isBound && d.isPaused.CompareAndSwap(true, false):
+		zap.L().Info("DG-LAB 已连接, 开始发电", zap.String("device", d.name))
+	}
+	return isBound
+}
+
+func newDevice(name string, session StimController, profile configModel.Device) *device {
+	d := &device{
+		name:    name,
+		session: session,
+		profile: profile,
+	}
+	d.isPaused.Store(true)
+	return d
+}
@@ -0,0 +1,33 @@
+package game
+
+type InvalidRuleError struct {
+	Message string
+}
+
+func (e InvalidRuleError) Error() string {
+	return e.Message
+}
+
+type InvalidRouteError struct {
+	Message string
+}
+
+func (e InvalidRouteError) Error() string {
+	return e.Message
+}
+
+type InvalidModifierError struct {
+	Message string
+}
+
+func (e InvalidModifierError) Error() string {
+	return e.Message
+}
+
+type InvalidLaneError struct {
+	Message string
+}
+
+func (e InvalidLaneError) Error() string {
+	return e.Message
+}
@@ -2,371 +2,412 @@ package game
 
 import (
 	configModel "IsaacCoyote/common/config/model"
+	"IsaacCoyote/common/expr"
 	"IsaacCoyote/common/isaac"
-	"IsaacCoyote/pkg/coyote"
 	"IsaacCoyote/pkg/coyote/enums"
-	"container/list"
+	"context"
 	"go.uber.org/zap"
+	"slices"
 	"sync"
 	"time"
 )
 
 type Game struct {
-	config        *configModel.Game
-	coyoteSession *coyote.Session
-
-	isaacListener *isaac.GameListener
-	playerInfo    playerInfo
+	config *configModel.Game
+
+	deviceLock sync.RWMutex
+	devices    []*device
+
+	events GameEventSource
+	clock  Clock
+
+	// players is keyed by isaac.PlayerRef.PlayerIndex, a stored playerInfo is
+	// never modified, updates replace it under playerLock
+	playerLock sync.Mutex
+	players    map[int]*playerInfo
+	floor      int
+
+	// rulesLock guards what Reload compiled from the config, it is swapped in whole
+	rulesLock        sync.RWMutex
+	rules            []*rule
+	strengthFormulaA *expr.Expr
+	strengthFormulaB *expr.Expr
+	lanes            laneSettings
+
+	// scheduleLock guards the tracks and everything in them
+	scheduleLock sync.Mutex
+	tracks       []*track
+
+	callbacksOnce sync.Once
+	runLock       sync.Mutex
+	cancelRun     context.CancelFunc
+	runDone       chan struct{}
+}
 
-	needContModeDecayCalc bool
-	collStrengthAddA      int
-	collStrengthAddB      int
+// Run blocks until ctx is cancelled or Shutdown is called, then drains the
+// queued pulses to zero strength once every worker goroutine has exited.
+func (g *Game) Run(ctx context.Context) error {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
 
-	dequeLock  sync.Mutex
-	pulseDeque *list.List
-}
+	g.runLock.Lock()
+	g.cancelRun = cancel
+	g.runDone = make(chan struct{})
+	runDone := g.runDone
+	g.runLock.Unlock()
+	defer close(runDone)
 
-func (g *Game) Run() error {
-	go g.dispatchPulse()
-	go g.continuousMode()
-	err := g.initCallbacks()
+	err := g.Reload()
+	if err != nil {
+		return err
+	}
+	g.callbacksOnce.Do(func() {
+		err = g.initCallbacks()
+	})
 	if err != nil {
 		return err
 	}
 
-	g.updateIndicator()
+	var wg sync.WaitGroup
+	for _, worker := range []func(context.Context){g.dispatchPulse, g.updateIndicator} {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			worker(ctx)
+		}()
+	}
+
+	<-ctx.Done()
+	wg.Wait()
+	g.drainPulse()
 	return nil
 }
 
-func (g *Game) dispatchPulse() {
-	ticker := time.NewTicker(200 * time.Millisecond)
-	defer ticker.Stop()
-
-	for range ticker.C {
-		if !g.coyoteSession.IsBound() {
-			continue
-		}
-
-		g.dequeLock.Lock()
-		segment := g.pulseDeque.Front()
-		if segment == nil {
-			g.dequeLock.Unlock()
-			err := g.coyoteSession.SetStrength(enums.ChannelTypeA, enums.StrengthActionSetTo, 0)
-			if err != nil {
-				zap.L().Error("Failed to set strength A", zap.Error(err))
-			}
-			err = g.coyoteSession.SetStrength(enums.ChannelTypeB, enums.StrengthActionSetTo, 0)
-			if err != nil {
-				zap.L().Error("Failed to set strength B", zap.Error(err))
-			}
-			continue
-		}
-		g.pulseDeque.Remove(segment)
-		g.dequeLock.Unlock()
-
-		strengthA := segment.Value.(pulseSegment).StrengthA
-		strengthB := segment.Value.(pulseSegment).StrengthB
-		currStrengthData := g.coyoteSession.GetStrengthData()
-		if strengthA > currStrengthData.MaxStrengthA {
-			strengthA = currStrengthData.MaxStrengthA
-		}
-		if strengthB > currStrengthData.MaxStrengthB {
-			strengthB = currStrengthData.MaxStrengthB
-		}
+// Shutdown stops a running Run and waits for it to return.
+func (g *Game) Shutdown(ctx context.Context) error {
+	g.runLock.Lock()
+	cancel, runDone := g.cancelRun, g.runDone
+	g.runLock.Unlock()
 
-		err := g.coyoteSession.SetStrength(enums.ChannelTypeA, enums.StrengthActionSetTo, strengthA)
-		if err != nil {
-			zap.L().Error("Failed to set strength A", zap.Error(err))
-		}
-		err = g.coyoteSession.SetStrength(enums.ChannelTypeB, enums.StrengthActionSetTo, strengthB)
-		if err != nil {
-			zap.L().Error("Failed to set strength B", zap.Error(err))
-		}
+	if cancel == nil {
+		return nil
+	}
+	cancel()
 
-		//g.coyoteSession.ClearPulse(enums.ChannelTypeA)
-		//g.coyoteSession.ClearPulse(enums.ChannelTypeB)
-		framesA := segment.Value.(pulseSegment).FramesA
-		framesB := segment.Value.(pulseSegment).FramesB
-		err = g.coyoteSession.AddPulse(enums.ChannelTypeA, framesA)
-		if err != nil {
-			zap.L().Error("failed to add pulse", zap.Error(err))
-		}
-		err = g.coyoteSession.AddPulse(enums.ChannelTypeB, framesB)
-		if err != nil {
-			zap.L().Error("failed to add pulse", zap.Error(err))
-		}
+	select {
+	case <-runDone:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
 	}
 }
 
-func (g *Game) initCallbacks() error {
-	_ = g.isaacListener.RegisterCallback(isaac.GameStartEvent, func(callbackData interface{}) {
-		zap.L().Debug("游戏开始")
-		startData := callbackData.(isaac.GameStartEventData)
-		if !startData.IsContinue {
-			g.reset()
-		}
-	})
-
-	_ = g.isaacListener.RegisterCallback(isaac.GameEndEvent, func(callbackData interface{}) {
-		g.reset()
-	})
+func (g *Game) drainPulse() {
+	g.scheduleLock.Lock()
+	for _, t := range g.tracks {
+		t.clear()
+	}
+	g.scheduleLock.Unlock()
 
-	_ = g.isaacListener.RegisterCallback(isaac.PlayerInfoUpdateEvent, func(callbackData interface{}) {
-		data := callbackData.(isaac.PlayerInfoUpdateEventData)
-		g.playerInfo.Health = data.Health
-		g.playerInfo.MaxHealth = data.MaxHealth
-		collectibles, err := parseCollectiblesString(data.Collectibles, g.isaacListener.ResourceManager)
-		if err != nil {
-			zap.L().Error("获取物品失败: ", zap.Error(err))
-		}
+	for _, d := range g.getDevices() {
+		d.drain()
+	}
+}
 
-		// Update collectibles and collectibles strength
-		if g.playerInfo.collString != data.Collectibles {
-			g.playerInfo.collString = data.Collectibles
-			g.playerInfo.Collectibles = collectibles
-
-			if g.config.OnNewCollectible.Enabled {
-				g.collStrengthAddA = 0
-				g.collStrengthAddB = 0
-				for _, item := range collectibles {
-					if quality := item.itemDetail.Quality; quality >= 0 {
-						if config, ok := g.config.OnNewCollectible.StrengthConfig[quality]; ok {
-							g.collStrengthAddA += config.StrengthAddA * item.num
-							g.collStrengthAddB += config.StrengthAddB * item.num
-						} else {
-							zap.L().Info("未配置的强度: " + item.itemDetail.Name)
-						}
-					}
-				}
-				zap.L().Debug("更新物品", zap.Any("data", collectibles))
-				zap.L().Debug("更新物品强度", zap.Any("strengthA", g.collStrengthAddA), zap.Any("strengthB", g.collStrengthAddB))
+// AddDevice starts fanning out pulses to session, a device with the same name is replaced.
+// A replaced device with the same session keeps its paused state, so reconnect messages are not logged twice.
+func (g *Game) AddDevice(name string, session StimController, profile configModel.Device) {
+	g.deviceLock.Lock()
+	defer g.deviceLock.Unlock()
+
+	newD := newDevice(name, session, profile)
+	for i, d := range g.devices {
+		if d.name == name {
+			if d.session == session {
+				newD.isPaused.Store(d.isPaused.Load())
 			}
+			g.devices[i] = newD
+			return
 		}
-	})
+	}
+	g.devices = append(g.devices, newD)
+}
 
-	_ = g.isaacListener.RegisterCallback(isaac.PlayerHurtEvent, func(interface{}) {
-		zap.L().Debug("玩家受伤")
-		if !g.config.OnHurt.Enabled || !g.coyoteSession.IsBound() {
+func (g *Game) RemoveDevice(name string) {
+	g.deviceLock.Lock()
+	defer g.deviceLock.Unlock()
+
+	for i, d := range g.devices {
+		if d.name == name {
+			g.devices = append(g.devices[:i], g.devices[i+1:]...)
+			go d.drain()
 			return
 		}
+	}
+}
 
-		var pulseIndexA int
-		var pulseIndexB int
-		var strengthA int
-		var strengthB int
-
-		if g.config.OnHurt.StrengthOperator == configModel.INCREMENT {
-			strengthA = g.getMinStrengthA() + g.config.OnHurt.StrengthA
-			strengthB = g.getMinStrengthB() + g.config.OnHurt.StrengthB
-		} else {
-			strengthA = g.config.OnHurt.StrengthA
-			strengthB = g.config.OnHurt.StrengthB
-		}
+func (g *Game) getDevices() []*device {
+	g.deviceLock.RLock()
+	defer g.deviceLock.RUnlock()
 
-		segmentList := list.New()
+	devices := make([]*device, len(g.devices))
+	copy(devices, g.devices)
+	return devices
+}
 
-		for duration := g.config.OnHurt.Duration; duration >= 0; duration -= 200 {
-			segment := pulseSegment{
-				FramesA:   nextTwoPulseFrames(g.config.OnHurt.PulseA.PulseWaveform, &pulseIndexA),
-				FramesB:   nextTwoPulseFrames(g.config.OnHurt.PulseB.PulseWaveform, &pulseIndexB),
-				StrengthA: strengthA,
-				StrengthB: strengthB,
-			}
-			segmentList.PushBack(segment)
+// isBound reports whether at least one device is bound.
+func (g *Game) isBound() bool {
+	for _, d := range g.getDevices() {
+		if d.session.IsBound() {
+			return true
 		}
-		g.dequeLock.Lock()
-		g.pulseDeque.PushFrontList(segmentList)
-		g.dequeLock.Unlock()
-		g.needContModeDecayCalc = true
-	})
+	}
+	return false
+}
 
-	_ = g.isaacListener.RegisterCallback(isaac.PlayerDeathEvent, func(interface{}) {
-		zap.L().Debug("玩家死亡")
-		if !g.config.OnDeath.Enabled || !g.coyoteSession.IsBound() {
+func (g *Game) dispatchPulse(ctx context.Context) {
+	ticker := g.clock.NewTicker(200 * time.Millisecond)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
 			return
+		case <-ticker.C():
 		}
 
-		var pulseIndexA int
-		var pulseIndexB int
-		var strengthA int
-		var strengthB int
-
-		if g.config.OnDeath.StrengthOperator == configModel.INCREMENT {
-			strengthA = g.getMinStrengthA() + g.config.OnDeath.StrengthA
-			strengthB = g.getMinStrengthB() + g.config.OnDeath.StrengthB
-		} else {
-			strengthA = g.config.OnDeath.StrengthA
-			strengthB = g.config.OnDeath.StrengthB
+		boundDevices := g.checkBound()
+		if len(boundDevices) == 0 {
+			continue
 		}
 
-		segmentList := list.New()
-		for duration := g.config.OnDeath.Duration; duration >= 0; duration -= 200 {
-			segment := pulseSegment{
-				FramesA:   nextTwoPulseFrames(g.config.OnDeath.PulseA.PulseWaveform, &pulseIndexA),
-				FramesB:   nextTwoPulseFrames(g.config.OnDeath.PulseB.PulseWaveform, &pulseIndexB),
-				StrengthA: strengthA,
-				StrengthB: strengthB,
+		lanes := g.getLaneSettings()
+		var segments []trackSegment
+		g.scheduleLock.Lock()
+		for _, t := range g.tracks {
+			ambientA, ambientB := g.ambientSettings(t)
+			segment := t.next(lanes, ambientA, ambientB)
+			if segment.hasA || segment.hasB {
+				segments = append(segments, segment)
 			}
-			segmentList.PushBack(segment)
 		}
-		g.dequeLock.Lock()
-
-		g.pulseDeque = list.New() //clear
-		g.pulseDeque.PushFrontList(segmentList)
-		g.dequeLock.Unlock()
+		g.scheduleLock.Unlock()
 
-		g.needContModeDecayCalc = true
-		g.playerInfo = playerInfo{}
-	})
-
-	_ = g.isaacListener.RegisterCallback(isaac.ManualRestartEvent, func(callbackData interface{}) {
-		zap.L().Debug("重开游戏")
-		if !g.config.OnManualRestart.Enabled || !g.coyoteSession.IsBound() {
-			return
+		for _, d := range boundDevices {
+			segment, ok := mixSegments(d.name, segments)
+			if !ok {
+				d.setZeroStrength()
+				continue
+			}
+			d.sendSegment(segment)
 		}
+	}
+}
 
-		var pulseIndexA int
-		var pulseIndexB int
-		var strengthA int
-		var strengthB int
-
-		if g.config.OnManualRestart.StrengthOperator == configModel.INCREMENT {
-			strengthA = g.config.BaseStrengthA + g.config.OnManualRestart.StrengthA
-			strengthB = g.config.BaseStrengthB + g.config.OnManualRestart.StrengthB
-		} else {
-			strengthA = g.config.OnManualRestart.StrengthA
-			strengthB = g.config.OnManualRestart.StrengthB
+// checkBound returns the bound devices. The pulse dispatch pauses while no app is connected,
+// queued pulses are dropped and continuous mode resumes where it stopped once one is bound again.
+func (g *Game) checkBound() []*device {
+	var boundDevices []*device
+	for _, d := range g.getDevices() {
+		if d.checkBound() {
+			boundDevices = append(boundDevices, d)
 		}
+	}
 
-		segmentList := list.New()
-		for duration := g.config.OnManualRestart.Duration; duration >= 0; duration -= 200 {
-			segment := pulseSegment{
-				FramesA:   nextTwoPulseFrames(g.config.OnManualRestart.PulseA.PulseWaveform, &pulseIndexA),
-				FramesB:   nextTwoPulseFrames(g.config.OnManualRestart.PulseB.PulseWaveform, &pulseIndexB),
-				StrengthA: strengthA,
-				StrengthB: strengthB,
-			}
-			segmentList.PushBack(segment)
+	if len(boundDevices) == 0 {
+		g.scheduleLock.Lock()
+		for _, t := range g.tracks {
+			t.clear()
 		}
-		g.dequeLock.Lock()
-		g.pulseDeque = list.New() //clear
-		g.pulseDeque.PushFrontList(segmentList)
-		g.dequeLock.Unlock()
-
-		g.needContModeDecayCalc = true
-	})
+		g.scheduleLock.Unlock()
+	}
+	return boundDevices
+}
 
+func (g *Game) initCallbacks() error {
+	for _, event := range isaac.Events {
+		err := g.events.RegisterCallback(event, func(callbackData interface{}) {
+			g.handleEvent(event, callbackData)
+		})
+		if err != nil {
+			return err
+		}
+	}
 	return nil
 }
 
-func (g *Game) continuousMode() {
-	ticker := time.NewTicker(200 * time.Millisecond)
-	defer ticker.Stop()
+// handleEvent updates the game state around the rules of event, so that the
+// rules of a restart already see the reset state and the rules of a death still
+// see the player it happened to.
+func (g *Game) handleEvent(event isaac.Event, callbackData interface{}) {
+	switch event {
+	case isaac.ManualRestartEvent:
+		zap.L().Debug("重开游戏")
+		g.reset()
+	case isaac.PlayerHurtEvent:
+		zap.L().Debug("玩家受伤")
+	case isaac.PlayerDeathEvent:
+		zap.L().Debug("玩家死亡")
+	case isaac.NewFloorEvent:
+		g.playerLock.Lock()
+		g.floor = callbackData.(isaac.NewFloorEventData).Stage
+		g.playerLock.Unlock()
+	}
 
-	var (
-		lastDecayTime = time.Now()
-		pulseIndexA   = 0
-		pulseIndexB   = 0
-		prevSegment   = pulseSegment{}
-	)
+	if g.isBound() {
+		g.applyRules(event, callbackData)
+	}
 
-	for range ticker.C {
-		if !g.config.ContinuousMode.Enabled || !g.coyoteSession.IsBound() || g.pulseDeque.Len() >= 100 {
-			continue
+	switch event {
+	case isaac.GameStartEvent:
+		zap.L().Debug("游戏开始")
+		startData := callbackData.(isaac.GameStartEventData)
+		if !startData.IsContinue {
+			g.reset()
 		}
+	case isaac.GameEndEvent:
+		g.reset()
+	case isaac.PlayerDeathEvent:
+		g.resetPlayer(callbackData.(isaac.PlayerDeathEventData).PlayerIndex)
+	case isaac.PlayerInfoUpdateEvent:
+		g.updatePlayerInfo(callbackData.(isaac.PlayerInfoUpdateEventData))
+	}
+}
 
-		if g.needContModeDecayCalc {
-			g.needContModeDecayCalc = false
-
-			lastSegment := g.pulseDeque.Front()
-			if lastSegment != nil {
-				prevSegment = lastSegment.Value.(pulseSegment)
-			} else {
-				prevSegment = pulseSegment{
-					StrengthA: g.coyoteSession.GetStrengthData().StrengthA,
-					StrengthB: g.coyoteSession.GetStrengthData().StrengthB,
-				}
-			}
-
-			pulseIndexB = 0
-			pulseIndexA = 0
-		}
+func (g *Game) updatePlayerInfo(data isaac.PlayerInfoUpdateEventData) {
+	g.playerLock.Lock()
+	var info playerInfo
+	if current, ok := g.players[data.PlayerIndex]; ok {
+		info = *current
+	}
+	info.Health = data.Health
+	info.MaxHealth = data.MaxHealth
+	info.SoulHearts = data.SoulHearts
+	info.BlackHearts = data.BlackHearts
+	info.BoneHearts = data.BoneHearts
+	info.EternalHearts = data.EternalHearts
+	info.GoldenHearts = data.GoldenHearts
+	info.RottenHearts = data.RottenHearts
+	info.PlayerType = data.PlayerType
+	info.Damage = data.Damage
+	info.Tears = data.Tears
+	info.Speed = data.Speed
+	info.Luck = data.Luck
+	info.Range = data.Range
+	info.PeakEffectiveHealth = max(info.PeakEffectiveHealth, info.EffectiveHealth())
+
+	// Update collectibles and collectibles strength
+	if !slices.Equal(info.collectibleCounts, data.Collectibles) {
+		collectibles := resolveCollectibles(data.Collectibles, g.events, g.config.OnNewCollectible.DefaultQuality)
+		info.collectibleCounts = slices.Clone(data.Collectibles)
+		info.Collectibles = collectibles
+		zap.L().Debug("更新物品", zap.Int("player", data.PlayerIndex+1), zap.Any("data", collectibles))
+		g.updateCollectibleStrength(data.PlayerIndex, &info)
+	}
 
-		// set pulse frame
-		segment := pulseSegment{
-			FramesA: nextTwoPulseFrames(g.config.ContinuousMode.PulseA.PulseWaveform, &pulseIndexA),
-			FramesB: nextTwoPulseFrames(g.config.ContinuousMode.PulseB.PulseWaveform, &pulseIndexB),
+	// the first update only tells which transformations the player already has
+	var gained []string
+	if info.updated {
+		for _, form := range data.Transformations {
+			if !slices.Contains(info.Transformations, form) {
+				gained = append(gained, form)
+			}
 		}
+	}
+	info.Transformations = slices.Clone(data.Transformations)
+	info.updated = true
+	// the transformations are diffed and stored in one step, so each is only gained once
+	g.players[data.PlayerIndex] = &info
+	g.playerLock.Unlock()
+
+	for _, form := range gained {
+		zap.L().Debug("玩家变身", zap.Int("player", data.PlayerIndex+1), zap.String("form", form))
+		g.handleEvent(isaac.PlayerTransformationEvent, isaac.PlayerTransformationEventData{
+			PlayerRef: data.PlayerRef,
+			Form:      form,
+		})
+	}
+}
 
-		//set strength
-		minA := g.getMinStrengthA()
-		minB := g.getMinStrengthB()
-
-		if g.config.ContinuousMode.DecayInterval > 0 {
-			segment.StrengthA = prevSegment.StrengthA
-			segment.StrengthB = prevSegment.StrengthB
+// getPlayer returns the state of the player, playerIndex is 0 for player 1.
+// The state must not be modified, updatePlayerInfo replaces it instead.
+func (g *Game) getPlayer(playerIndex int) *playerInfo {
+	g.playerLock.Lock()
+	defer g.playerLock.Unlock()
 
-			intervalDuration := time.Duration(g.config.ContinuousMode.DecayInterval) * time.Millisecond
-			elapsed := time.Now().Sub(lastDecayTime)
-			if elapsed >= intervalDuration {
-				decayCount := int(elapsed / intervalDuration)
-				segment.StrengthA -= g.config.ContinuousMode.DecayValue * decayCount
-				segment.StrengthB -= g.config.ContinuousMode.DecayValue * decayCount
+	info, ok := g.players[playerIndex]
+	if !ok {
+		info = &playerInfo{}
+		g.players[playerIndex] = info
+	}
+	return info
+}
 
-				lastDecayTime = lastDecayTime.Add(time.Duration(decayCount) * intervalDuration)
-			}
-		} else {
-			segment.StrengthA, segment.StrengthB = minA, minB
-		}
+func (g *Game) resetPlayer(playerIndex int) {
+	g.playerLock.Lock()
+	defer g.playerLock.Unlock()
 
-		segment.StrengthA = max(segment.StrengthA, minA)
-		segment.StrengthB = max(segment.StrengthB, minB)
+	delete(g.players, playerIndex)
+}
 
-		g.dequeLock.Lock()
-		g.pulseDeque.PushBack(segment)
-		prevSegment = segment
-		g.dequeLock.Unlock()
+// ambientSettings is continuous mode for the player of t, scheduleLock must be held.
+func (g *Game) ambientSettings(t *track) (ambientA ambientSettings, ambientB ambientSettings) {
+	config := g.config.ContinuousMode
+	info := g.getPlayer(t.playerIndex())
+	ambientA = ambientSettings{
+		enabled:       config.Enabled,
+		decayInterval: time.Duration(config.DecayInterval) * time.Millisecond,
+		decayValue:    config.DecayValue,
+		pulse:         config.PulseA.PulseWaveform,
+		minStrength:   g.getMinStrength(info, enums.ChannelTypeA),
 	}
+	ambientB = ambientA
+	ambientB.pulse = config.PulseB.PulseWaveform
+	ambientB.minStrength = g.getMinStrength(info, enums.ChannelTypeB)
+	return ambientA, ambientB
 }
 
-func (g *Game) updateIndicator() {
-	ticker := time.NewTicker(200 * time.Millisecond)
+func (g *Game) updateIndicator(ctx context.Context) {
+	ticker := g.clock.NewTicker(200 * time.Millisecond)
 	defer ticker.Stop()
 
-	for range ticker.C {
-		if !g.coyoteSession.IsBound() {
-			continue
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C():
 		}
 
-		strengthData := g.coyoteSession.GetStrengthData()
-		g.isaacListener.AddUpdateIndicatorMsg(strengthData.StrengthA, strengthData.StrengthB)
+		// the indicator follows the first bound device
+		for _, d := range g.getDevices() {
+			if d.session.IsBound() {
+				strengthData := d.session.GetStrengthData()
+				g.events.AddUpdateIndicatorMsg(strengthData.StrengthA, strengthData.StrengthB)
+				break
+			}
+		}
 	}
 }
 
-func (g *Game) getMinStrengthA() int {
-	return g.config.BaseStrengthA +
-		g.config.StrengthPerHealthA*(g.playerInfo.MaxHealth-g.playerInfo.Health) +
-		g.collStrengthAddA
-}
-
-func (g *Game) getMinStrengthB() int {
-	return g.config.BaseStrengthB +
-		g.config.StrengthPerHealthB*(g.playerInfo.MaxHealth-g.playerInfo.Health) +
-		g.collStrengthAddB
+func (g *Game) reset() {
+	g.playerLock.Lock()
+	g.players = make(map[int]*playerInfo)
+	g.floor = 0
+	g.playerLock.Unlock()
 }
 
-func (g *Game) reset() {
-	g.needContModeDecayCalc = true
-	g.collStrengthAddA, g.collStrengthAddB = 0, 0
-	g.playerInfo = playerInfo{}
+// SetClock replaces the clock of the pulse workers, it must be called before Run.
+func (g *Game) SetClock(clock Clock) {
+	g.clock = clock
 }
 
-func NewGame(config *configModel.Game, coyoteSession *coyote.Session, isaacListener *isaac.GameListener) *Game {
+func NewGame(config *configModel.Game, events GameEventSource) *Game {
 	return &Game{
-		config:        config,
-		coyoteSession: coyoteSession,
-		isaacListener: isaacListener,
+		config: config,
+		events: events,
+		clock:  realClock{},
+		lanes:  newLaneSettings(configModel.Scheduler{}),
 
-		pulseDeque: list.New(),
+		players: make(map[int]*playerInfo),
 	}
 }
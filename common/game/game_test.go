@@ -0,0 +1,341 @@
+package game_test
+
+import (
+	configModel "IsaacCoyote/common/config/model"
+	"IsaacCoyote/common/game"
+	"IsaacCoyote/common/game/gametest"
+	"IsaacCoyote/common/isaac"
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
+const segment = 200 * time.Millisecond
+
+type harness struct {
+	t          *testing.T
+	config     *configModel.Game
+	game       *game.Game
+	events     *gametest.FakeEventSource
+	clock      *gametest.FakeClock
+	controller *gametest.FakeController
+}
+
+// newHarness runs a game with config and one device with the given app limits.
+func newHarness(t *testing.T, config string, maxStrengthA int, maxStrengthB int) *harness {
+	t.Helper()
+	var gameConfig configModel.Game
+	if err := yaml.Unmarshal([]byte(config), &gameConfig); err != nil {
+		t.Fatal(err)
+	}
+
+	h := &harness{
+		t:          t,
+		config:     &gameConfig,
+		events:     gametest.NewFakeEventSource(),
+		clock:      gametest.NewFakeClock(time.Unix(0, 0)),
+		controller: gametest.NewFakeController(maxStrengthA, maxStrengthB),
+	}
+	g := game.NewGame(&gameConfig, h.events)
+	g.SetClock(h.clock)
+	g.AddDevice("coyote", h.controller, configModel.Device{ScaleA: 1, ScaleB: 1})
+	h.game = g
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error, 1)
+	go func() {
+		done <- g.Run(ctx)
+	}()
+	t.Cleanup(func() {
+		cancel()
+		if err := <-done; err != nil {
+			t.Errorf("Run: %v", err)
+		}
+	})
+
+	// dispatchPulse and updateIndicator create their tickers once the callbacks are registered
+	h.waitFor("the workers", func() bool {
+		return h.clock.Tickers() == 2
+	})
+	return h
+}
+
+func (h *harness) waitFor(what string, cond func() bool) {
+	h.t.Helper()
+	deadline := time.Now().Add(5 * time.Second)
+	for !cond() {
+		if time.Now().After(deadline) {
+			h.t.Fatalf("timed out waiting for %s", what)
+		}
+		time.Sleep(time.Millisecond)
+	}
+}
+
+// tick plays one segment and returns the strengths the device was set to.
+func (h *harness) tick() [2]int {
+	h.t.Helper()
+	n := len(h.controller.Strengths())
+	h.clock.Advance(segment)
+	h.waitFor("a segment", func() bool {
+		return len(h.controller.Strengths()) >= n+2
+	})
+	strengths := h.controller.Strengths()[n : n+2]
+	return [2]int{strengths[0].Strength, strengths[1].Strength}
+}
+
+type step struct {
+	// event is emitted before the segments are played, if set
+	event isaac.Event
+	data  any
+	// want are the strengths of channel A and B in the following segments
+	want [][2]int
+}
+
+func hurt() isaac.PlayerHurtEventData {
+	return isaac.PlayerHurtEventData{Damage: 1}
+}
+
+func health(health int, maxHealth int) isaac.PlayerInfoUpdateEventData {
+	return isaac.PlayerInfoUpdateEventData{Health: health, MaxHealth: maxHealth}
+}
+
+func TestDispatchPulse(t *testing.T) {
+	tests := []struct {
+		name   string
+		config string
+		limits [2]int
+		steps  []step
+	}{
+		{
+			name: "continuous mode decays to the minimum strength",
+			config: `
+base_strength_A: 10
+base_strength_B: 5
+strength_per_health_A: 2
+strength_per_health_B: 1
+continuous_mode:
+  enabled: true
+  decay_interval: 400
+  decay_value: 5
+  pulse_A: '["0A0A0A0A64646464"]'
+  pulse_B: '["0A0A0A0A64646464"]'
+`,
+			limits: [2]int{100, 100},
+			steps: []step{
+				{event: isaac.PlayerInfoUpdateEvent, data: health(6, 6), want: [][2]int{{10, 5}}},
+				{event: isaac.PlayerInfoUpdateEvent, data: health(2, 6), want: [][2]int{{18, 9}}},
+				{event: isaac.PlayerInfoUpdateEvent, data: health(6, 6), want: [][2]int{{18, 9}, {13, 5}, {13, 5}, {10, 5}, {10, 5}}},
+			},
+		},
+		{
+			name: "continuous mode decays from the strength an event ended at",
+			config: `
+base_strength_A: 10
+base_strength_B: 5
+strength_per_health_A: 2
+strength_per_health_B: 1
+continuous_mode:
+  enabled: true
+  decay_interval: 400
+  decay_value: 5
+rules:
+  - name: hurt
+    event: PlayerHurtEvent
+    duration: 200
+    strength_operator: ABSOLUTE
+    strength_A: 30
+    strength_B: 20
+`,
+			limits: [2]int{100, 100},
+			steps: []step{
+				{event: isaac.PlayerInfoUpdateEvent, data: health(2, 6), want: [][2]int{{18, 9}}},
+				{event: isaac.PlayerInfoUpdateEvent, data: health(6, 6)},
+				// the decay keeps the progress it had towards its next step when it was interrupted
+				{event: isaac.PlayerHurtEvent, data: hurt(), want: [][2]int{{30, 20}, {30, 20}, {25, 15}, {25, 15}, {20, 10}, {20, 10}, {15, 5}, {15, 5}, {10, 5}}},
+			},
+		},
+		{
+			name: "death preempts hurt and hurt preempts continuous mode",
+			config: `
+base_strength_A: 10
+base_strength_B: 10
+continuous_mode:
+  enabled: true
+  pulse_A: '["0A0A0A0A64646464"]'
+  pulse_B: '["0A0A0A0A64646464"]'
+rules:
+  - name: hurt
+    event: PlayerHurtEvent
+    duration: 600
+    strength_operator: ABSOLUTE
+    strength_A: 30
+    strength_B: 20
+  - name: death
+    event: PlayerDeathEvent
+    duration: 200
+    strength_operator: ABSOLUTE
+    strength_A: 80
+    strength_B: 60
+    lane: CRITICAL
+    queue: REPLACE_ALL
+`,
+			limits: [2]int{100, 100},
+			steps: []step{
+				{want: [][2]int{{10, 10}}},
+				{event: isaac.PlayerHurtEvent, data: hurt(), want: [][2]int{{30, 20}, {30, 20}}},
+				// the hurt pulses queued before the death are dropped
+				{event: isaac.PlayerDeathEvent, data: isaac.PlayerDeathEventData{}, want: [][2]int{{80, 60}, {80, 60}, {10, 10}, {10, 10}}},
+			},
+		},
+		{
+			name: "legacy death clears the queued hurt",
+			config: `
+base_strength_A: 10
+base_strength_B: 10
+continuous_mode:
+  enabled: true
+on_hurt:
+  enabled: true
+  duration: 600
+  strength_operator: ABSOLUTE
+  strength_A: 30
+  strength_B: 20
+on_death:
+  enabled: true
+  duration: 200
+  strength_operator: ABSOLUTE
+  strength_A: 80
+  strength_B: 60
+`,
+			limits: [2]int{100, 100},
+			steps: []step{
+				{event: isaac.PlayerHurtEvent, data: hurt(), want: [][2]int{{30, 20}}},
+				{event: isaac.PlayerDeathEvent, data: isaac.PlayerDeathEventData{}, want: [][2]int{{80, 60}, {80, 60}, {10, 10}, {10, 10}}},
+			},
+		},
+		{
+			name: "strength is clamped to the limits of the app",
+			config: `
+base_strength_A: 10
+base_strength_B: 30
+continuous_mode:
+  enabled: true
+rules:
+  - name: hurt
+    event: PlayerHurtEvent
+    duration: 0
+    strength_operator: ABSOLUTE
+    strength_A: 80
+    strength_B: 80
+`,
+			limits: [2]int{50, 20},
+			steps: []step{
+				{want: [][2]int{{10, 20}}},
+				{event: isaac.PlayerHurtEvent, data: hurt(), want: [][2]int{{50, 20}, {10, 20}}},
+			},
+		},
+		{
+			name: "idle channels are set to zero without continuous mode",
+			config: `
+base_strength_A: 10
+base_strength_B: 10
+rules:
+  - name: hurt
+    event: PlayerHurtEvent
+    duration: 200
+    strength_A: 5
+    strength_B: 0
+`,
+			limits: [2]int{100, 100},
+			steps: []step{
+				{want: [][2]int{{0, 0}}},
+				{event: isaac.PlayerHurtEvent, data: hurt(), want: [][2]int{{15, 10}, {15, 10}, {0, 0}}},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			h := newHarness(t, tt.config, tt.limits[0], tt.limits[1])
+			for i, s := range tt.steps {
+				if s.event != "" {
+					h.events.Emit(s.event, s.data)
+				}
+				for j, want := range s.want {
+					if got := h.tick(); got != want {
+						t.Fatalf("step %d segment %d: strength = %v, want %v", i, j, got, want)
+					}
+				}
+			}
+		})
+	}
+}
+
+// the config is edited between segments, like the config manager does on a hot reload
+func TestReloadKeepsLastValidConfig(t *testing.T) {
+	h := newHarness(t, `
+base_strength_A: 10
+base_strength_B: 10
+strength_formula_A: "20"
+continuous_mode:
+  enabled: true
+`, 100, 100)
+	if got := h.tick(); got != [2]int{20, 10} {
+		t.Fatalf("strength = %v, want [20 10]", got)
+	}
+
+	h.config.StrengthFormulaA = configModel.NumberExpression(30)
+	h.config.Scheduler.Event.Merge = "UNKNOWN"
+	if err := h.game.Reload(); err == nil {
+		t.Fatal("Reload accepted an unknown merge policy")
+	}
+	if got := h.tick(); got != [2]int{20, 10} {
+		t.Fatalf("strength after a failed reload = %v, want [20 10]", got)
+	}
+
+	h.config.Scheduler.Event.Merge = configModel.MERGE_MAX
+	if err := h.game.Reload(); err != nil {
+		t.Fatal(err)
+	}
+	if got := h.tick(); got != [2]int{30, 10} {
+		t.Fatalf("strength after reload = %v, want [30 10]", got)
+	}
+}
+
+// updates of the same player may race, the transformation they report is still gained once
+func TestTransformationFiresOnce(t *testing.T) {
+	h := newHarness(t, `
+base_strength_A: 10
+base_strength_B: 10
+rules:
+  - name: guppy
+    event: PlayerTransformationEvent
+    duration: 200
+    strength_operator: ABSOLUTE
+    strength_A: 50
+    strength_B: 50
+    queue: APPEND
+`, 100, 100)
+	h.events.Emit(isaac.PlayerInfoUpdateEvent, health(6, 6))
+
+	transformed := health(6, 6)
+	transformed.Transformations = isaac.StringList{"GUPPY"}
+	var wg sync.WaitGroup
+	for range 8 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			h.events.Emit(isaac.PlayerInfoUpdateEvent, transformed)
+		}()
+	}
+	wg.Wait()
+
+	for i, want := range [][2]int{{50, 50}, {50, 50}, {0, 0}} {
+		if got := h.tick(); got != want {
+			t.Fatalf("segment %d: strength = %v, want %v", i, got, want)
+		}
+	}
+}
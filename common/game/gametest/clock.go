@@ -0,0 +1,98 @@
+package gametest
+
+import (
+	"IsaacCoyote/common/game"
+	"sync"
+	"time"
+)
+
+var _ game.Clock = (*FakeClock)(nil)
+
+// FakeClock is a game.Clock that only moves on Advance.
+type FakeClock struct {
+	lock    sync.Mutex
+	now     time.Time
+	tickers []*fakeTicker
+}
+
+func (c *FakeClock) Now() time.Time {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	return c.now
+}
+
+func (c *FakeClock) NewTicker(d time.Duration) game.Ticker {
+	if d <= 0 {
+		panic("non-positive interval for FakeClock.NewTicker")
+	}
+
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	t := &fakeTicker{
+		clock:    c,
+		c:        make(chan time.Time, 1),
+		interval: d,
+		next:     c.now.Add(d),
+	}
+	c.tickers = append(c.tickers, t)
+	return t
+}
+
+// Advance moves the clock forward and fires every ticker that became due.
+// Like time.Ticker, ticks are dropped when the receiver is behind.
+func (c *FakeClock) Advance(d time.Duration) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	c.now = c.now.Add(d)
+	for _, t := range c.tickers {
+		for !t.next.After(c.now) {
+			select {
+			case t.c <- t.next:
+			default:
+			}
+			t.next = t.next.Add(t.interval)
+		}
+	}
+}
+
+// Tickers returns how many tickers are running, Advance only reaches the ones already created.
+func (c *FakeClock) Tickers() int {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	return len(c.tickers)
+}
+
+func (c *FakeClock) removeTicker(t *fakeTicker) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	for i, ticker := range c.tickers {
+		if ticker == t {
+			c.tickers = append(c.tickers[:i], c.tickers[i+1:]...)
+			return
+		}
+	}
+}
+
+type fakeTicker struct {
+	clock    *FakeClock
+	c        chan time.Time
+	interval time.Duration
+	next     time.Time
+}
+
+func (t *fakeTicker) C() <-chan time.Time {
+	return t.c
+}
+
+func (t *fakeTicker) Stop() {
+	t.clock.removeTicker(t)
+}
+
+func NewFakeClock(now time.Time) *FakeClock {
+	return &FakeClock{now: now}
+}
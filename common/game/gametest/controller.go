@@ -0,0 +1,154 @@
+// Package gametest provides in-memory fakes for driving game.Game without
+// a DG-LAB app or a running Isaac.
+package gametest
+
+import (
+	"IsaacCoyote/common/game"
+	"IsaacCoyote/pkg/coyote"
+	"IsaacCoyote/pkg/coyote/enums"
+	"sync"
+)
+
+var _ game.StimController = (*FakeController)(nil)
+
+// Pulse is one AddPulse call recorded by FakeController.
+type Pulse struct {
+	Channel  enums.ChannelType
+	Waveform coyote.PulseWaveform
+}
+
+// Strength is one SetStrength call recorded by FakeController, after clamping.
+type Strength struct {
+	Channel  enums.ChannelType
+	Strength int
+}
+
+// FakeController is a game.StimController that records what it was sent.
+// SetStrength with StrengthActionSetTo is clamped to the limits like the app does.
+type FakeController struct {
+	lock         sync.Mutex
+	bound        bool
+	strengthData coyote.StrengthData
+	strengths    []Strength
+	pulses       []Pulse
+	clears       []enums.ChannelType
+}
+
+func (f *FakeController) IsBound() bool {
+	f.lock.Lock()
+	defer f.lock.Unlock()
+
+	return f.bound
+}
+
+// SetBound simulates the app binding or disconnecting.
+func (f *FakeController) SetBound(bound bool) {
+	f.lock.Lock()
+	defer f.lock.Unlock()
+
+	f.bound = bound
+}
+
+// SetLimits simulates the app reporting new max strengths.
+func (f *FakeController) SetLimits(maxStrengthA int, maxStrengthB int) {
+	f.lock.Lock()
+	defer f.lock.Unlock()
+
+	f.strengthData.MaxStrengthA = maxStrengthA
+	f.strengthData.MaxStrengthB = maxStrengthB
+}
+
+func (f *FakeController) SetStrength(channel enums.ChannelType, action enums.StrengthAction, strength int) error {
+	f.lock.Lock()
+	defer f.lock.Unlock()
+
+	if !f.bound {
+		return coyote.NotBindError{Message: "Fake controller is not bound"}
+	}
+
+	current, limit := &f.strengthData.StrengthA, f.strengthData.MaxStrengthA
+	if channel == enums.ChannelTypeB {
+		current, limit = &f.strengthData.StrengthB, f.strengthData.MaxStrengthB
+	}
+	switch action {
+	case enums.StrengthActionIncrease:
+		*current += strength
+	case enums.StrengthActionDecrease:
+		*current -= strength
+	default:
+		*current = strength
+	}
+	*current = min(max(*current, 0), limit)
+	f.strengths = append(f.strengths, Strength{Channel: channel, Strength: *current})
+	return nil
+}
+
+func (f *FakeController) AddPulse(channel enums.ChannelType, waveform coyote.PulseWaveform) error {
+	f.lock.Lock()
+	defer f.lock.Unlock()
+
+	if !f.bound {
+		return coyote.NotBindError{Message: "Fake controller is not bound"}
+	}
+	f.pulses = append(f.pulses, Pulse{Channel: channel, Waveform: waveform})
+	return nil
+}
+
+func (f *FakeController) ClearPulse(channel enums.ChannelType) error {
+	f.lock.Lock()
+	defer f.lock.Unlock()
+
+	if !f.bound {
+		return coyote.NotBindError{Message: "Fake controller is not bound"}
+	}
+	f.clears = append(f.clears, channel)
+	return nil
+}
+
+func (f *FakeController) GetStrengthData() coyote.StrengthData {
+	f.lock.Lock()
+	defer f.lock.Unlock()
+
+	return f.strengthData
+}
+
+// Strengths returns every SetStrength call so far.
+func (f *FakeController) Strengths() []Strength {
+	f.lock.Lock()
+	defer f.lock.Unlock()
+
+	strengths := make([]Strength, len(f.strengths))
+	copy(strengths, f.strengths)
+	return strengths
+}
+
+// Pulses returns every AddPulse call so far.
+func (f *FakeController) Pulses() []Pulse {
+	f.lock.Lock()
+	defer f.lock.Unlock()
+
+	pulses := make([]Pulse, len(f.pulses))
+	copy(pulses, f.pulses)
+	return pulses
+}
+
+// Clears returns the channels of every ClearPulse call so far.
+func (f *FakeController) Clears() []enums.ChannelType {
+	f.lock.Lock()
+	defer f.lock.Unlock()
+
+	clears := make([]enums.ChannelType, len(f.clears))
+	copy(clears, f.clears)
+	return clears
+}
+
+// NewFakeController returns a bound controller with the given app limits.
+func NewFakeController(maxStrengthA int, maxStrengthB int) *FakeController {
+	return &FakeController{
+		bound: true,
+		strengthData: coyote.StrengthData{
+			MaxStrengthA: maxStrengthA,
+			MaxStrengthB: maxStrengthB,
+		},
+	}
+}
@@ -0,0 +1,101 @@
+package gametest
+
+import (
+	"IsaacCoyote/common/game"
+	"IsaacCoyote/common/isaac"
+	"fmt"
+	"sync"
+)
+
+var _ game.GameEventSource = (*FakeEventSource)(nil)
+
+// Indicator is one AddUpdateIndicatorMsg call recorded by FakeEventSource.
+type Indicator struct {
+	StrengthA int
+	StrengthB int
+}
+
+// FakeEventSource is a game.GameEventSource fed by Emit instead of the mod.
+type FakeEventSource struct {
+	lock       sync.Mutex
+	callbacks  map[isaac.Event][]isaac.CallbackFunc
+	items      map[int]isaac.ItemDetail
+	indicators []Indicator
+}
+
+func (f *FakeEventSource) RegisterCallback(eventType isaac.Event, callback isaac.CallbackFunc) error {
+	if callback == nil {
+		return isaac.InvalidCallbackError{Message: "Callback is nil"}
+	}
+
+	f.lock.Lock()
+	defer f.lock.Unlock()
+
+	f.callbacks[eventType] = append(f.callbacks[eventType], callback)
+	return nil
+}
+
+// Emit runs the callbacks of eventType synchronously, unlike GameListener.
+func (f *FakeEventSource) Emit(eventType isaac.Event, callbackData interface{}) {
+	f.lock.Lock()
+	callbacks := f.callbacks[eventType]
+	f.lock.Unlock()
+
+	for _, callback := range callbacks {
+		callback(callbackData)
+	}
+}
+
+func (f *FakeEventSource) AddUpdateIndicatorMsg(strengthA int, strengthB int) {
+	f.lock.Lock()
+	defer f.lock.Unlock()
+
+	f.indicators = append(f.indicators, Indicator{StrengthA: strengthA, StrengthB: strengthB})
+}
+
+// Indicators returns every indicator update so far.
+func (f *FakeEventSource) Indicators() []Indicator {
+	f.lock.Lock()
+	defer f.lock.Unlock()
+
+	indicators := make([]Indicator, len(f.indicators))
+	copy(indicators, f.indicators)
+	return indicators
+}
+
+// AddItem makes item resolvable by GetItemByID and GetItemByName.
+func (f *FakeEventSource) AddItem(item isaac.ItemDetail) {
+	f.lock.Lock()
+	defer f.lock.Unlock()
+
+	f.items[item.ID] = item
+}
+
+func (f *FakeEventSource) GetItemByID(itemID int) (isaac.ItemDetail, error) {
+	f.lock.Lock()
+	defer f.lock.Unlock()
+
+	if item, ok := f.items[itemID]; ok {
+		return item, nil
+	}
+	return isaac.ItemDetail{}, isaac.NoSuchItemError{Message: fmt.Sprintf("No such item: %d", itemID)}
+}
+
+func (f *FakeEventSource) GetItemByName(itemName string) (isaac.ItemDetail, error) {
+	f.lock.Lock()
+	defer f.lock.Unlock()
+
+	for _, item := range f.items {
+		if item.Name == itemName {
+			return item, nil
+		}
+	}
+	return isaac.ItemDetail{}, isaac.NoSuchItemError{Message: "No such item: " + itemName}
+}
+
+func NewFakeEventSource() *FakeEventSource {
+	return &FakeEventSource{
+		callbacks: make(map[isaac.Event][]isaac.CallbackFunc),
+		items:     make(map[int]isaac.ItemDetail),
+	}
+}
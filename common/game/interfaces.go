@@ -0,0 +1,60 @@
+package game
+
+import (
+	"IsaacCoyote/common/isaac"
+	"IsaacCoyote/pkg/coyote"
+	"IsaacCoyote/pkg/coyote/enums"
+	"time"
+)
+
+// StimController is the part of a DG-LAB device the game drives.
+// coyote.Session and coyote.Client both implement it.
+type StimController interface {
+	IsBound() bool
+	SetStrength(channel enums.ChannelType, action enums.StrengthAction, strength int) error
+	AddPulse(channel enums.ChannelType, waveform coyote.PulseWaveform) error
+	ClearPulse(channel enums.ChannelType) error
+	// GetStrengthData returns the current strength and the limits set in the app.
+	GetStrengthData() coyote.StrengthData
+}
+
+// GameEventSource delivers game events to the game, isaac.GameListener implements it.
+type GameEventSource interface {
+	RegisterCallback(eventType isaac.Event, callback isaac.CallbackFunc) error
+	AddUpdateIndicatorMsg(strengthA int, strengthB int)
+	GetItemByID(itemID int) (isaac.ItemDetail, error)
+	GetItemByName(itemName string) (isaac.ItemDetail, error)
+}
+
+// Clock lets the pulse workers run on a fake time source.
+type Clock interface {
+	Now() time.Time
+	NewTicker(d time.Duration) Ticker
+}
+
+type Ticker interface {
+	C() <-chan time.Time
+	Stop()
+}
+
+type realClock struct{}
+
+func (realClock) Now() time.Time {
+	return time.Now()
+}
+
+func (realClock) NewTicker(d time.Duration) Ticker {
+	return realTicker{time.NewTicker(d)}
+}
+
+type realTicker struct {
+	ticker *time.Ticker
+}
+
+func (t realTicker) C() <-chan time.Time {
+	return t.ticker.C
+}
+
+func (t realTicker) Stop() {
+	t.ticker.Stop()
+}
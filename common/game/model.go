@@ -13,11 +13,44 @@ type pulseSegment struct {
 	FramesB   []coyote.PulseFrame
 }
 
+// playerInfo mirrors isaac.PlayerInfoUpdateEventData, hearts are in half hearts
+// except bone, golden and rotten hearts.
 type playerInfo struct {
-	Health       int
-	MaxHealth    int
-	Collectibles []itemDetailWrapper
-	collString   string
+	Health        int
+	MaxHealth     int
+	SoulHearts    int
+	BlackHearts   int
+	BoneHearts    int
+	EternalHearts int
+	GoldenHearts  int
+	RottenHearts  int
+
+	PlayerType int
+	Damage     float64
+	Tears      float64
+	Speed      float64
+	Luck       float64
+	Range      float64
+
+	// PeakEffectiveHealth is the highest EffectiveHealth of the run so far
+	PeakEffectiveHealth int
+
+	Collectibles       []itemDetailWrapper
+	collectibleCounts  []isaac.CollectibleCount
+	collectibleBonuses []collectibleBonus
+	collStrengthAddA   int
+	collStrengthAddB   int
+
+	Transformations []string
+	// updated is set by the first PlayerInfoUpdateEvent of the player
+	updated bool
+}
+
+// EffectiveHealth is roughly how many half hearts of damage the player can take:
+// red, soul and eternal hearts plus one hit per bone heart.
+// It is what keeps dropping for characters without red health like Blue Baby.
+func (p *playerInfo) EffectiveHealth() int {
+	return p.Health + p.SoulHearts + p.EternalHearts + p.BoneHearts
 }
 
 type itemDetailWrapper struct {
@@ -0,0 +1,335 @@
+package game
+
+import (
+	configModel "IsaacCoyote/common/config/model"
+	"IsaacCoyote/common/isaac"
+	"IsaacCoyote/pkg/coyote/enums"
+	"fmt"
+	"go.uber.org/zap"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+// rule is a compiled configModel.Rule.
+type rule struct {
+	configModel.Rule
+	event      isaac.Event
+	conditions []condition
+}
+
+// condition compares one field of the event payload, e.g. "damage >= 2".
+// The & operator holds when any of the given bits is set, e.g. "flags & DAMAGE_FIRE|DAMAGE_EXPLOSION",
+// !& when none of them is.
+type condition struct {
+	raw   string
+	field string
+	op    string
+	value any // float64, bool or string
+}
+
+// operators are tried in order, so the two character ones come first.
+var conditionOperators = []string{">=", "<=", "==", "!=", "!&", ">", "<", "&"}
+
+func parseCondition(raw string) (condition, error) {
+	for _, op := range conditionOperators {
+		field, rawValue, ok := strings.Cut(raw, op)
+		if !ok {
+			continue
+		}
+		field, rawValue = strings.TrimSpace(field), strings.TrimSpace(rawValue)
+		if field == "" || rawValue == "" {
+			break
+		}
+
+		value, err := parseConditionValue(rawValue, op == "&" || op == "!&")
+		if err != nil {
+			return condition{}, fmt.Errorf("invalid condition %q: %w", raw, err)
+		}
+		return condition{raw: raw, field: field, op: op, value: value}, nil
+	}
+	return condition{}, fmt.Errorf("invalid condition %q", raw)
+}
+
+func parseConditionValue(rawValue string, isBitMask bool) (any, error) {
+	if isBitMask {
+		var mask int
+		for _, name := range strings.Split(rawValue, "|") {
+			name = strings.TrimSpace(name)
+			if flag, ok := isaac.DamageFlags[name]; ok {
+				mask |= int(flag)
+				continue
+			}
+			bit, err := strconv.Atoi(name)
+			if err != nil {
+				return nil, fmt.Errorf("unknown flag %q", name)
+			}
+			mask |= bit
+		}
+		return float64(mask), nil
+	}
+
+	if number, err := strconv.ParseFloat(rawValue, 64); err == nil {
+		return number, nil
+	}
+	if flag, ok := isaac.DamageFlags[rawValue]; ok {
+		return float64(flag), nil
+	}
+	if boolean, err := strconv.ParseBool(rawValue); err == nil {
+		return boolean, nil
+	}
+	if unquoted, err := strconv.Unquote(rawValue); err == nil {
+		return unquoted, nil
+	}
+	return rawValue, nil
+}
+
+func (c condition) match(payload map[string]any) bool {
+	fieldValue, ok := payload[c.field]
+	if !ok {
+		return false
+	}
+
+	switch expected := c.value.(type) {
+	case float64:
+		actual, ok := fieldValue.(float64)
+		if !ok {
+			return false
+		}
+		switch c.op {
+		case ">=":
+			return actual >= expected
+		case "<=":
+			return actual <= expected
+		case ">":
+			return actual > expected
+		case "<":
+			return actual < expected
+		case "==":
+			return actual == expected
+		case "!=":
+			return actual != expected
+		case "&":
+			return int64(actual)&int64(expected) != 0
+		case "!&":
+			return int64(actual)&int64(expected) == 0
+		}
+	default:
+		switch c.op {
+		case "==":
+			return fieldValue == expected
+		case "!=":
+			return fieldValue != expected
+		}
+	}
+	return false
+}
+
+// match checks the conditions against the json form of the event payload.
+func (r *rule) match(callbackData any) bool {
+	if len(r.conditions) == 0 {
+		return true
+	}
+
+	payload := payloadFields(callbackData)
+	for _, c := range r.conditions {
+		if !c.match(payload) {
+			return false
+		}
+	}
+	return true
+}
+
+func compileRule(config configModel.Rule) (*rule, error) {
+	event := isaac.Event(config.Event)
+	if !event.IsValid() {
+		return nil, fmt.Errorf("unknown event %q", config.Event)
+	}
+	switch config.Queue {
+	case configModel.APPEND, configModel.PREEMPT, configModel.REPLACE, configModel.CLEAR,
+		configModel.REPLACE_ALL, configModel.CLEAR_ALL:
+	default:
+		return nil, fmt.Errorf("unknown queue policy %q", config.Queue)
+	}
+	switch config.Lane {
+	case configModel.LANE_EVENT, configModel.LANE_CRITICAL:
+	default:
+		return nil, fmt.Errorf("unknown lane %q, rules can use EVENT or CRITICAL", config.Lane)
+	}
+
+	r := &rule{
+		Rule:  config,
+		event: event,
+	}
+	for _, rawCondition := range config.Conditions {
+		c, err := parseCondition(rawCondition)
+		if err != nil {
+			return nil, err
+		}
+		r.conditions = append(r.conditions, c)
+	}
+
+	varNames := ruleVarNames(event)
+	for _, expression := range []configModel.Expression{config.StrengthA, config.StrengthB, config.Duration} {
+		if expression.Expr == nil {
+			continue
+		}
+		err := expression.Check(func(name string) bool {
+			return slices.Contains(varNames, name)
+		})
+		if err != nil {
+			return nil, err
+		}
+	}
+	return r, nil
+}
+
+// Reload validates the strength formulas, compiles the rules and applies them
+// together with the lane settings and player routes of the current config.
+// On error the game keeps using what the last successful Reload compiled.
+func (g *Game) Reload() error {
+	formulaA, formulaB := g.config.StrengthFormulaA.Expr, g.config.StrengthFormulaB.Expr
+	err := checkStrengthFormulas(formulaA, formulaB)
+	if err != nil {
+		return InvalidRuleError{
+			Message: "strength formula: " + err.Error(),
+		}
+	}
+
+	configs := g.config.LegacyRules()
+	if len(configs) != 0 {
+		zap.L().Warn("on_hurt / on_death / on_manual_restart 已弃用, 请改用 rules")
+	}
+	configs = append(configs, g.config.Rules...)
+
+	var rules []*rule
+	for i, config := range configs {
+		if !config.Enabled {
+			continue
+		}
+		r, err := compileRule(config)
+		if err != nil {
+			return InvalidRuleError{
+				Message: fmt.Sprintf("rule %d (%s): %s", i, config.Name, err),
+			}
+		}
+		rules = append(rules, r)
+	}
+
+	err = checkRoutes(g.config.Players)
+	if err != nil {
+		return err
+	}
+	err = checkModifiers(g.config.OnNewCollectible.Modifiers)
+	if err != nil {
+		return err
+	}
+	lanes := newLaneSettings(g.config.Scheduler)
+	err = checkLaneSettings(lanes)
+	if err != nil {
+		return err
+	}
+
+	g.rulesLock.Lock()
+	g.rules = rules
+	g.strengthFormulaA, g.strengthFormulaB = formulaA, formulaB
+	g.lanes = lanes
+	g.rulesLock.Unlock()
+	g.setRoutes(g.config.Players)
+
+	// strength_config and the modifiers may have changed
+	g.playerLock.Lock()
+	for playerIndex, info := range g.players {
+		updated := *info
+		g.updateCollectibleStrength(playerIndex, &updated)
+		g.players[playerIndex] = &updated
+	}
+	g.playerLock.Unlock()
+	return nil
+}
+
+func (g *Game) getRules(event isaac.Event) []*rule {
+	g.rulesLock.RLock()
+	defer g.rulesLock.RUnlock()
+
+	var rules []*rule
+	for _, r := range g.rules {
+		if r.event == event {
+			rules = append(rules, r)
+		}
+	}
+	return rules
+}
+
+// applyRules queues the pulses of every rule of event whose conditions hold,
+// up to the first matching final rule, on the tracks of the player of the event.
+func (g *Game) applyRules(event isaac.Event, callbackData any) {
+	var matched []*rule
+	for _, r := range g.getRules(event) {
+		if !r.match(callbackData) {
+			continue
+		}
+		zap.L().Debug("触发规则", zap.String("rule", r.Name), zap.String("event", event.String()))
+		matched = append(matched, r)
+		if r.Final {
+			break
+		}
+	}
+	if len(matched) == 0 {
+		return
+	}
+
+	for _, t := range g.tracksFor(callbackData) {
+		info := g.getPlayer(t.playerIndex())
+		for _, r := range matched {
+			slotsA, slotsB := g.ruleSlots(info, r, callbackData)
+			g.queueSlots(t, r, slotsA, slotsB)
+		}
+	}
+}
+
+func (g *Game) ruleSlots(info *playerInfo, r *rule, callbackData any) (slotsA []stimSlot, slotsB []stimSlot) {
+	if r.Queue == configModel.CLEAR || r.Queue == configModel.CLEAR_ALL {
+		return nil, nil
+	}
+
+	var pulseIndexA int
+	var pulseIndexB int
+	var strengthA int
+	var strengthB int
+
+	varsA := g.ruleVars(info, enums.ChannelTypeA, callbackData)
+	strengthA = evalInt(r.StrengthA.Expr, varsA)
+	strengthB = evalInt(r.StrengthB.Expr, g.ruleVars(info, enums.ChannelTypeB, callbackData))
+	if r.StrengthOperator == configModel.INCREMENT {
+		strengthA += g.getMinStrength(info, enums.ChannelTypeA)
+		strengthB += g.getMinStrength(info, enums.ChannelTypeB)
+	}
+
+	l := laneOf(r.Lane)
+	// the channel specific variables of the duration are the ones of channel A
+	for duration := evalInt(r.Duration.Expr, varsA); duration >= 0; duration -= 200 {
+		slotsA = append(slotsA, stimSlot{
+			Strength: strengthA,
+			Frames:   nextTwoPulseFrames(r.PulseA.PulseWaveform, &pulseIndexA),
+			Rule:     r.Name,
+			lane:     l,
+		})
+		slotsB = append(slotsB, stimSlot{
+			Strength: strengthB,
+			Frames:   nextTwoPulseFrames(r.PulseB.PulseWaveform, &pulseIndexB),
+			Rule:     r.Name,
+			lane:     l,
+		})
+	}
+	return slotsA, slotsB
+}
+
+func (g *Game) queueSlots(t *track, r *rule, slotsA []stimSlot, slotsB []stimSlot) {
+	g.scheduleLock.Lock()
+	defer g.scheduleLock.Unlock()
+
+	l := laneOf(r.Lane)
+	t.timelineA.queue(l, r.Queue, slotsA)
+	t.timelineB.queue(l, r.Queue, slotsB)
+}
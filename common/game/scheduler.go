@@ -0,0 +1,252 @@
+package game
+
+import (
+	configModel "IsaacCoyote/common/config/model"
+	"IsaacCoyote/pkg/coyote"
+	"time"
+)
+
+const segmentDuration = 200 * time.Millisecond
+
+// lane indexes the lanes of a channelTimeline from low to high priority.
+type lane int
+
+const (
+	laneAmbient lane = iota
+	laneEvent
+	laneCritical
+	laneCount
+)
+
+var laneNames = [laneCount]configModel.Lane{configModel.LANE_AMBIENT, configModel.LANE_EVENT, configModel.LANE_CRITICAL}
+
+func laneOf(name configModel.Lane) lane {
+	if name == configModel.LANE_CRITICAL {
+		return laneCritical
+	}
+	return laneEvent
+}
+
+// laneSettings are the merge policy and preemption of each lane, the ambient lane has none.
+type laneSettings [laneCount]configModel.LaneConfig
+
+var defaultLaneConfig = configModel.LaneConfig{
+	Merge:   configModel.MERGE_MAX,
+	Preempt: true,
+}
+
+func newLaneSettings(config configModel.Scheduler) laneSettings {
+	var settings laneSettings
+	settings[laneEvent] = config.Event
+	settings[laneCritical] = config.Critical
+	for l := laneEvent; l < laneCount; l++ {
+		// the section is missing from the config
+		if settings[l].Merge == "" {
+			settings[l] = defaultLaneConfig
+		}
+	}
+	return settings
+}
+
+func (g *Game) getLaneSettings() laneSettings {
+	g.rulesLock.RLock()
+	defer g.rulesLock.RUnlock()
+
+	return g.lanes
+}
+
+func checkLaneSettings(settings laneSettings) error {
+	for l := laneEvent; l < laneCount; l++ {
+		switch settings[l].Merge {
+		case configModel.MERGE_MAX, configModel.MERGE_SUM, configModel.MERGE_REPLACE:
+		default:
+			return InvalidLaneError{
+				Message: "lane " + string(laneNames[l]) + ": unknown merge policy " + string(settings[l].Merge),
+			}
+		}
+	}
+	return nil
+}
+
+// ambientSettings is continuous mode for one channel of a player.
+type ambientSettings struct {
+	enabled       bool
+	decayInterval time.Duration
+	decayValue    int
+	pulse         coyote.PulseWaveform
+	minStrength   int
+}
+
+// stimSlot is what one lane plays on one channel for one segment.
+type stimSlot struct {
+	Strength int
+	Frames   []coyote.PulseFrame
+	// Rule is the name of the rule that queued the slot, empty for continuous mode
+	Rule string
+	lane lane
+}
+
+// ambientCurve is the continuous mode of a channel. It only advances while the
+// ambient lane plays, so a decay interrupted by a preempting lane resumes where it stopped.
+type ambientCurve struct {
+	level      int
+	pulseIndex int
+	// decayElapsed is the play time since the last decay step
+	decayElapsed time.Duration
+}
+
+func (a *ambientCurve) next(settings ambientSettings) stimSlot {
+	if settings.decayInterval > 0 {
+		a.decayElapsed += segmentDuration
+		steps := int(a.decayElapsed / settings.decayInterval)
+		a.decayElapsed -= time.Duration(steps) * settings.decayInterval
+		a.level -= settings.decayValue * steps
+	} else {
+		a.level = settings.minStrength
+	}
+	a.level = max(a.level, settings.minStrength)
+
+	return stimSlot{
+		Strength: a.level,
+		Frames:   nextTwoPulseFrames(settings.pulse, &a.pulseIndex),
+		lane:     laneAmbient,
+	}
+}
+
+// channelTimeline schedules one channel of a track.
+type channelTimeline struct {
+	// lanes[laneAmbient] stays empty, ambient generates that lane
+	lanes   [laneCount][]stimSlot
+	ambient ambientCurve
+
+	// last is what played in the previous segment, higherActive whether a lane
+	// above ambient took part in it
+	last         stimSlot
+	higherActive bool
+}
+
+// queue puts slots into the lane by policy, REPLACE and CLEAR leave the other lanes alone,
+// REPLACE_ALL and CLEAR_ALL clear them too.
+func (c *channelTimeline) queue(l lane, policy configModel.QueuePolicy, slots []stimSlot) {
+	switch policy {
+	case configModel.APPEND:
+		c.lanes[l] = append(c.lanes[l], slots...)
+	case configModel.PREEMPT:
+		c.lanes[l] = append(slots, c.lanes[l]...)
+	case configModel.REPLACE, configModel.CLEAR:
+		c.lanes[l] = slots
+	case configModel.REPLACE_ALL, configModel.CLEAR_ALL:
+		c.clear()
+		c.lanes[l] = slots
+	}
+}
+
+// clear drops everything queued, the ambient curve is kept.
+func (c *channelTimeline) clear() {
+	for l := range c.lanes {
+		c.lanes[l] = nil
+	}
+}
+
+// next pops the slot the channel plays in this segment, ok is false when every lane is idle.
+func (c *channelTimeline) next(lanes laneSettings, ambient ambientSettings) (slot stimSlot, ok bool) {
+	var active [laneCount]bool
+	paused := false
+	for l := laneCount - 1; l >= laneAmbient; l-- {
+		if l == laneAmbient {
+			active[l] = !paused && ambient.enabled
+		} else {
+			active[l] = !paused && len(c.lanes[l]) > 0
+		}
+		if active[l] && lanes[l].Preempt {
+			paused = true
+		}
+	}
+
+	// the decay continues from the strength the higher lanes ended with
+	higherActive := active[laneEvent] || active[laneCritical]
+	if c.higherActive && !higherActive {
+		c.ambient.level = max(c.ambient.level, c.last.Strength)
+	}
+	c.higherActive = higherActive
+
+	for l := laneAmbient; l < laneCount; l++ {
+		if !active[l] {
+			continue
+		}
+		var s stimSlot
+		if l == laneAmbient {
+			s = c.ambient.next(ambient)
+		} else {
+			s = c.lanes[l][0]
+			c.lanes[l] = c.lanes[l][1:]
+		}
+		if !ok {
+			slot, ok = s, true
+			continue
+		}
+		slot = mergeSlots(lanes[l].Merge, slot, s)
+	}
+	c.last = slot
+	return slot, ok
+}
+
+func mergeSlots(policy configModel.MergePolicy, below stimSlot, above stimSlot) stimSlot {
+	switch policy {
+	case configModel.MERGE_SUM:
+		above.Strength += below.Strength
+		return above
+	case configModel.MERGE_REPLACE:
+		return above
+	}
+	if below.Strength > above.Strength {
+		return below
+	}
+	return above
+}
+
+// timelineEntry is a run of segments playing the same on one channel.
+type timelineEntry struct {
+	Lane     configModel.Lane `json:"lane"`
+	Rule     string           `json:"rule,omitempty"`
+	Strength int              `json:"strength"`
+	// Duration in milliseconds
+	Duration int `json:"duration"`
+}
+
+// preview plays a copy of the channel for at most limit segments, it stops early
+// once only continuous mode is left and has decayed to the minimum strength.
+func (c *channelTimeline) preview(lanes laneSettings, ambient ambientSettings, limit int) []timelineEntry {
+	sim := *c
+	var entries []timelineEntry
+	for range limit {
+		slot, ok := sim.next(lanes, ambient)
+		if !ok {
+			break
+		}
+		last := len(entries) - 1
+		if last >= 0 && entries[last].Lane == laneNames[slot.lane] && entries[last].Rule == slot.Rule && entries[last].Strength == slot.Strength {
+			entries[last].Duration += int(segmentDuration.Milliseconds())
+		} else {
+			entries = append(entries, timelineEntry{
+				Lane:     laneNames[slot.lane],
+				Rule:     slot.Rule,
+				Strength: slot.Strength,
+				Duration: int(segmentDuration.Milliseconds()),
+			})
+		}
+		if !sim.higherActive && len(sim.lanes[laneEvent]) == 0 && len(sim.lanes[laneCritical]) == 0 && sim.ambient.level <= ambient.minStrength {
+			break
+		}
+	}
+	return entries
+}
+
+// queuedDuration is how many milliseconds each lane above ambient has queued.
+func (c *channelTimeline) queuedDuration() map[configModel.Lane]int {
+	queued := make(map[configModel.Lane]int)
+	for l := laneEvent; l < laneCount; l++ {
+		queued[laneNames[l]] = len(c.lanes[l]) * int(segmentDuration.Milliseconds())
+	}
+	return queued
+}
@@ -0,0 +1,164 @@
+package game
+
+import (
+	"IsaacCoyote/common/expr"
+	"IsaacCoyote/common/isaac"
+	"IsaacCoyote/pkg/coyote/enums"
+	"encoding/json"
+	"go.uber.org/zap"
+	"math"
+	"slices"
+)
+
+// defaultStrengthFormula is the minimum strength when strength_formula_A/B is not set.
+var defaultStrengthFormula = expr.MustCompile("base + perHealth * lostHealth + collectibles")
+
+// playerVarNames are the variables of every strength expression, see playerVars.
+var playerVarNames = []string{
+	"base", "perHealth", "collectibles", "health", "maxHealth",
+	"lostHealth", "lostHearts", "collectibleCount", "floor",
+	"soulHearts", "blackHearts", "boneHearts", "eternalHearts", "goldenHearts", "rottenHearts",
+	"effectiveHealth", "peakEffectiveHealth", "lostEffectiveHealth",
+	"playerType", "playerDamage", "tears", "speed", "luck", "range",
+}
+
+// playerVars are evaluated for one player and channel, base, perHealth and collectibles
+// are the values of that channel. Health is in half hearts.
+func (g *Game) playerVars(info *playerInfo, channel enums.ChannelType) map[string]float64 {
+	base, perHealth, collectibles := g.config.BaseStrengthA, g.config.StrengthPerHealthA, info.collStrengthAddA
+	if channel == enums.ChannelTypeB {
+		base, perHealth, collectibles = g.config.BaseStrengthB, g.config.StrengthPerHealthB, info.collStrengthAddB
+	}
+
+	g.playerLock.Lock()
+	floor := g.floor
+	g.playerLock.Unlock()
+
+	var collectibleCount int
+	for _, item := range info.Collectibles {
+		collectibleCount += item.num
+	}
+	lostHealth := info.MaxHealth - info.Health
+	effectiveHealth := info.EffectiveHealth()
+
+	return map[string]float64{
+		"base":             float64(base),
+		"perHealth":        float64(perHealth),
+		"collectibles":     float64(collectibles),
+		"health":           float64(info.Health),
+		"maxHealth":        float64(info.MaxHealth),
+		"lostHealth":       float64(lostHealth),
+		"lostHearts":       float64(lostHealth) / 2,
+		"collectibleCount": float64(collectibleCount),
+		"floor":            float64(floor),
+
+		"soulHearts":          float64(info.SoulHearts),
+		"blackHearts":         float64(info.BlackHearts),
+		"boneHearts":          float64(info.BoneHearts),
+		"eternalHearts":       float64(info.EternalHearts),
+		"goldenHearts":        float64(info.GoldenHearts),
+		"rottenHearts":        float64(info.RottenHearts),
+		"effectiveHealth":     float64(effectiveHealth),
+		"peakEffectiveHealth": float64(info.PeakEffectiveHealth),
+		"lostEffectiveHealth": float64(max(info.PeakEffectiveHealth-effectiveHealth, 0)),
+
+		"playerType":   float64(info.PlayerType),
+		"playerDamage": info.Damage,
+		"tears":        info.Tears,
+		"speed":        info.Speed,
+		"luck":         info.Luck,
+		"range":        info.Range,
+	}
+}
+
+// ruleVars adds minStrength and the numeric fields of the event payload to the player variables.
+func (g *Game) ruleVars(info *playerInfo, channel enums.ChannelType, callbackData any) map[string]float64 {
+	vars := g.playerVars(info, channel)
+	vars["minStrength"] = float64(g.getMinStrength(info, channel))
+	for name, value := range payloadFields(callbackData) {
+		switch v := value.(type) {
+		case float64:
+			vars[name] = v
+		case bool:
+			vars[name] = 0
+			if v {
+				vars[name] = 1
+			}
+		}
+	}
+	return vars
+}
+
+// ruleVarNames lists the variables ruleVars defines for event.
+func ruleVarNames(event isaac.Event) []string {
+	names := append(slices.Clone(playerVarNames), "minStrength")
+	for name, value := range payloadFields(isaac.NewEventData(event)) {
+		switch value.(type) {
+		case float64, bool:
+			names = append(names, name)
+		}
+	}
+	return names
+}
+
+// payloadFields is the json form of the event payload, keyed by the json field names.
+func payloadFields(callbackData any) map[string]any {
+	payload := make(map[string]any)
+	if callbackData == nil {
+		return payload
+	}
+	rawData, err := json.Marshal(callbackData)
+	if err != nil {
+		return payload
+	}
+	_ = json.Unmarshal(rawData, &payload)
+	return payload
+}
+
+func (g *Game) strengthFormula(channel enums.ChannelType) *expr.Expr {
+	g.rulesLock.RLock()
+	formula := g.strengthFormulaA
+	if channel == enums.ChannelTypeB {
+		formula = g.strengthFormulaB
+	}
+	g.rulesLock.RUnlock()
+
+	if formula == nil {
+		return defaultStrengthFormula
+	}
+	return formula
+}
+
+func (g *Game) getMinStrength(info *playerInfo, channel enums.ChannelType) int {
+	formula := g.strengthFormula(channel)
+	return evalInt(formula, g.playerVars(info, channel))
+}
+
+// evalInt rounds the result to a strength or duration, a failed evaluation counts as 0.
+func evalInt(formula *expr.Expr, vars map[string]float64) int {
+	if formula == nil {
+		return 0
+	}
+	value, err := formula.Eval(vars)
+	if err != nil {
+		zap.L().Error("计算表达式失败", zap.String("expression", formula.String()), zap.Error(err))
+		return 0
+	}
+	return int(math.Round(value))
+}
+
+// checkStrengthFormulas makes sure the strength formulas only use player variables.
+func checkStrengthFormulas(formulas ...*expr.Expr) error {
+	for _, formula := range formulas {
+		if formula == nil {
+			continue
+		}
+		err := formula.Check(func(name string) bool {
+			return slices.Contains(playerVarNames, name)
+		})
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,134 @@
+package game
+
+import (
+	configModel "IsaacCoyote/common/config/model"
+	"IsaacCoyote/common/isaac"
+	"IsaacCoyote/pkg/coyote/enums"
+	"fmt"
+	"slices"
+)
+
+// defaultRoutes let player 1 drive every channel of every device.
+var defaultRoutes = []configModel.PlayerRoute{{Player: 1, Channel: configModel.ROUTE_BOTH}}
+
+// track schedules the channels of one player route. All fields are guarded by scheduleLock.
+type track struct {
+	route     configModel.PlayerRoute
+	timelineA channelTimeline
+	timelineB channelTimeline
+}
+
+// playerIndex is 0 for player 1, like isaac.PlayerRef.
+func (t *track) playerIndex() int {
+	return t.route.Player - 1
+}
+
+func newTrack(route configModel.PlayerRoute) *track {
+	return &track{
+		route: route,
+	}
+}
+
+func (t *track) clear() {
+	t.timelineA.clear()
+	t.timelineB.clear()
+}
+
+// next pops the segment of the track, each channel is only set when one of its lanes plays.
+func (t *track) next(lanes laneSettings, ambientA ambientSettings, ambientB ambientSettings) trackSegment {
+	slotA, hasA := t.timelineA.next(lanes, ambientA)
+	slotB, hasB := t.timelineB.next(lanes, ambientB)
+	return trackSegment{
+		route: t.route,
+		hasA:  hasA,
+		hasB:  hasB,
+		segment: pulseSegment{
+			StrengthA: slotA.Strength,
+			StrengthB: slotB.Strength,
+			FramesA:   slotA.Frames,
+			FramesB:   slotB.Frames,
+		},
+	}
+}
+
+func checkRoutes(routes []configModel.PlayerRoute) error {
+	for i, route := range routes {
+		if route.Player < 1 {
+			return InvalidRouteError{
+				Message: fmt.Sprintf("player route %d: invalid player %d", i, route.Player),
+			}
+		}
+		switch route.Channel {
+		case configModel.ROUTE_A, configModel.ROUTE_B, configModel.ROUTE_BOTH:
+		default:
+			return InvalidRouteError{
+				Message: fmt.Sprintf("player route %d: unknown channel %q", i, route.Channel),
+			}
+		}
+	}
+	return nil
+}
+
+// setRoutes rebuilds the tracks when the routes changed, queued pulses and the
+// continuous mode curves are dropped then.
+func (g *Game) setRoutes(routes []configModel.PlayerRoute) {
+	if len(routes) == 0 {
+		routes = defaultRoutes
+	}
+
+	g.scheduleLock.Lock()
+	defer g.scheduleLock.Unlock()
+
+	if slices.EqualFunc(g.tracks, routes, func(t *track, route configModel.PlayerRoute) bool {
+		return t.route == route
+	}) {
+		return
+	}
+	g.tracks = make([]*track, 0, len(routes))
+	for _, route := range routes {
+		g.tracks = append(g.tracks, newTrack(route))
+	}
+}
+
+// tracksFor returns the tracks of the player of an event, every track for events
+// that do not belong to a player.
+func (g *Game) tracksFor(callbackData any) []*track {
+	g.scheduleLock.Lock()
+	defer g.scheduleLock.Unlock()
+
+	playerEvent, ok := callbackData.(isaac.PlayerEvent)
+	if !ok {
+		return slices.Clone(g.tracks)
+	}
+	var tracks []*track
+	for _, t := range g.tracks {
+		if t.playerIndex() == playerEvent.Player().PlayerIndex {
+			tracks = append(tracks, t)
+		}
+	}
+	return tracks
+}
+
+// trackSegment is the segment a track plays in the current tick.
+type trackSegment struct {
+	route      configModel.PlayerRoute
+	hasA, hasB bool
+	segment    pulseSegment
+}
+
+// mixSegments picks, per channel of the device, the strongest segment of the
+// tracks routed to it. ok is false when no track drives the device right now.
+func mixSegments(deviceName string, segments []trackSegment) (mixed pulseSegment, ok bool) {
+	var hasA, hasB bool
+	for _, s := range segments {
+		if s.hasA && s.route.Drives(deviceName, enums.ChannelTypeA) && (!hasA || s.segment.StrengthA > mixed.StrengthA) {
+			mixed.StrengthA, mixed.FramesA = s.segment.StrengthA, s.segment.FramesA
+			hasA = true
+		}
+		if s.hasB && s.route.Drives(deviceName, enums.ChannelTypeB) && (!hasB || s.segment.StrengthB > mixed.StrengthB) {
+			mixed.StrengthB, mixed.FramesB = s.segment.StrengthB, s.segment.FramesB
+			hasB = true
+		}
+	}
+	return mixed, hasA || hasB
+}
@@ -3,9 +3,9 @@ package game
 import (
 	"IsaacCoyote/common/isaac"
 	"IsaacCoyote/pkg/coyote"
-	"regexp"
-	"strconv"
-	"strings"
+	"errors"
+	"fmt"
+	"go.uber.org/zap"
 )
 
 func nextTwoPulseFrames(pulse []coyote.PulseFrame, index *int) []coyote.PulseFrame {
@@ -22,32 +22,48 @@ func nextTwoPulseFrames(pulse []coyote.PulseFrame, index *int) []coyote.PulseFra
 	return []coyote.PulseFrame{frame0, frame1}
 }
 
-func parseCollectiblesString(s string, resManager *isaac.ResourceManager) ([]itemDetailWrapper, error) {
-	re := regexp.MustCompile(`([^,:]+):([^,]+)`)
-	matches := re.FindAllStringSubmatch(s, -1)
-
-	result := make([]itemDetailWrapper, 0)
-	for _, match := range matches {
-		w := itemDetailWrapper{}
-		if len(match) < 3 {
+// resolveCollectibles looks the collectibles up by ID, modded items by name.
+// Items missing from the resources get defaultQuality.
+func resolveCollectibles(collectibles []isaac.CollectibleCount, events GameEventSource, defaultQuality int) []itemDetailWrapper {
+	result := make([]itemDetailWrapper, 0, len(collectibles))
+	for _, collectible := range collectibles {
+		if collectible.Num <= 0 {
 			continue
 		}
 
-		item, err := resManager.GetItemByName(strings.TrimSpace(match[1]))
-		if err != nil {
-			// Ignore items glitched without IDs or other information.
-			continue
+		var item isaac.ItemDetail
+		var err error
+		if collectible.Name != "" {
+			item, err = events.GetItemByName(collectible.Name)
+			// modded IDs are only valid for this run
+			item.ID = collectible.ID
+		} else {
+			item, err = events.GetItemByID(collectible.ID)
 		}
-		num, err := strconv.Atoi(strings.TrimSpace(match[2]))
 		if err != nil {
-			return nil, err
+			item = isaac.ItemDetail{
+				ID:      collectible.ID,
+				Name:    fmt.Sprintf("#%d", collectible.ID),
+				Quality: defaultQuality,
+			}
 		}
-
-		w.itemDetail = item
-		w.num = num
-
-		result = append(result, w)
+		result = append(result, itemDetailWrapper{
+			itemDetail: item,
+			num:        collectible.Num,
+		})
 	}
+	return result
+}
 
-	return result, nil
+// logSessionError logs a failed session call, a session that is not bound is expected
+// while the app reconnects and is not logged.
+func logSessionError(msg string, err error) {
+	if err == nil {
+		return
+	}
+	var notBindErr coyote.NotBindError
+	if errors.As(err, &notBindErr) {
+		return
+	}
+	zap.L().Error(msg, zap.Error(err))
 }
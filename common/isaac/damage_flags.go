@@ -0,0 +1,75 @@
+package isaac
+
+// DamageFlag is the DamageFlag bit set passed to MC_ENTITY_TAKE_DMG.
+type DamageFlag int
+
+const (
+	DAMAGE_NOKILL DamageFlag = 1 << iota
+	DAMAGE_FIRE
+	DAMAGE_EXPLOSION
+	DAMAGE_LASER
+	DAMAGE_ACID
+	DAMAGE_RED_HEARTS
+	DAMAGE_COUNTDOWN
+	DAMAGE_SPIKES
+	DAMAGE_CLONES
+	DAMAGE_POOP
+	DAMAGE_DEVIL
+	DAMAGE_ISSAC_HEART
+	DAMAGE_TNT
+	DAMAGE_INVINCIBLE
+	DAMAGE_SPAWN_FLY
+	DAMAGE_POISON_BURN
+	DAMAGE_CURSED_DOOR
+	DAMAGE_TIMER
+	DAMAGE_IV_BAG
+	DAMAGE_PITFALL
+	DAMAGE_CHEST
+	DAMAGE_BOOGER
+	DAMAGE_SPAWN_BLACK_HEART
+	DAMAGE_CRUSH
+	DAMAGE_NO_MODIFIERS
+	DAMAGE_SPAWN_RED_HEART
+	DAMAGE_SPAWN_COIN
+	DAMAGE_NO_PENALTIES
+	DAMAGE_SPAWN_TEMP_HEART
+	DAMAGE_IGNORE_ARMOR
+	DAMAGE_SPAWN_CARD
+	DAMAGE_SPAWN_RUNE
+)
+
+// DamageFlags maps the names used by the game API to their bit.
+var DamageFlags = map[string]DamageFlag{
+	"DAMAGE_NOKILL":            DAMAGE_NOKILL,
+	"DAMAGE_FIRE":              DAMAGE_FIRE,
+	"DAMAGE_EXPLOSION":         DAMAGE_EXPLOSION,
+	"DAMAGE_LASER":             DAMAGE_LASER,
+	"DAMAGE_ACID":              DAMAGE_ACID,
+	"DAMAGE_RED_HEARTS":        DAMAGE_RED_HEARTS,
+	"DAMAGE_COUNTDOWN":         DAMAGE_COUNTDOWN,
+	"DAMAGE_SPIKES":            DAMAGE_SPIKES,
+	"DAMAGE_CLONES":            DAMAGE_CLONES,
+	"DAMAGE_POOP":              DAMAGE_POOP,
+	"DAMAGE_DEVIL":             DAMAGE_DEVIL,
+	"DAMAGE_ISSAC_HEART":       DAMAGE_ISSAC_HEART,
+	"DAMAGE_TNT":               DAMAGE_TNT,
+	"DAMAGE_INVINCIBLE":        DAMAGE_INVINCIBLE,
+	"DAMAGE_SPAWN_FLY":         DAMAGE_SPAWN_FLY,
+	"DAMAGE_POISON_BURN":       DAMAGE_POISON_BURN,
+	"DAMAGE_CURSED_DOOR":       DAMAGE_CURSED_DOOR,
+	"DAMAGE_TIMER":             DAMAGE_TIMER,
+	"DAMAGE_IV_BAG":            DAMAGE_IV_BAG,
+	"DAMAGE_PITFALL":           DAMAGE_PITFALL,
+	"DAMAGE_CHEST":             DAMAGE_CHEST,
+	"DAMAGE_BOOGER":            DAMAGE_BOOGER,
+	"DAMAGE_SPAWN_BLACK_HEART": DAMAGE_SPAWN_BLACK_HEART,
+	"DAMAGE_CRUSH":             DAMAGE_CRUSH,
+	"DAMAGE_NO_MODIFIERS":      DAMAGE_NO_MODIFIERS,
+	"DAMAGE_SPAWN_RED_HEART":   DAMAGE_SPAWN_RED_HEART,
+	"DAMAGE_SPAWN_COIN":        DAMAGE_SPAWN_COIN,
+	"DAMAGE_NO_PENALTIES":      DAMAGE_NO_PENALTIES,
+	"DAMAGE_SPAWN_TEMP_HEART":  DAMAGE_SPAWN_TEMP_HEART,
+	"DAMAGE_IGNORE_ARMOR":      DAMAGE_IGNORE_ARMOR,
+	"DAMAGE_SPAWN_CARD":        DAMAGE_SPAWN_CARD,
+	"DAMAGE_SPAWN_RUNE":        DAMAGE_SPAWN_RUNE,
+}
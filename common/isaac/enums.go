@@ -1,5 +1,7 @@
 package isaac
 
+import "slices"
+
 type Event string
 
 const (
@@ -11,12 +13,35 @@ const (
 	GameExitEvent         Event = "GameExitEvent"
 	GameEndEvent          Event = "GameEndEvent"
 	PlayerInfoUpdateEvent Event = "PlayerInfoUpdateEvent"
+	NewCollectibleEvent   Event = "NewCollectibleEvent"
+	RoomClearEvent        Event = "RoomClearEvent"
+	BossKilledEvent       Event = "BossKilledEvent"
+	NewFloorEvent         Event = "NewFloorEvent"
+	ActiveItemUsedEvent   Event = "ActiveItemUsedEvent"
+	PillUsedEvent         Event = "PillUsedEvent"
+	CardUsedEvent         Event = "CardUsedEvent"
+	PickupCollectedEvent  Event = "PickupCollectedEvent"
+	DevilDealTakenEvent   Event = "DevilDealTakenEvent"
+	// PlayerTransformationEvent is derived from PlayerInfoUpdateEvent by the game
+	PlayerTransformationEvent Event = "PlayerTransformationEvent"
 )
 
 func (e Event) String() string {
 	return string(e)
 }
 
+// Events lists every event a callback can be registered for.
+var Events = []Event{
+	ModInitEvent, PlayerHurtEvent, PlayerDeathEvent, ManualRestartEvent, GameStartEvent,
+	GameExitEvent, GameEndEvent, PlayerInfoUpdateEvent, NewCollectibleEvent, RoomClearEvent,
+	BossKilledEvent, NewFloorEvent, ActiveItemUsedEvent, PillUsedEvent, CardUsedEvent,
+	PickupCollectedEvent, DevilDealTakenEvent, PlayerTransformationEvent,
+}
+
+func (e Event) IsValid() bool {
+	return slices.Contains(Events, e)
+}
+
 type MsgType string
 
 const (
@@ -25,3 +50,14 @@ const (
 	ConnectMsg         = "connect"
 	HeartbeatMsg       = "heartbeat"
 )
+
+// PickupKind is what PickupCollectedEvent counts.
+type PickupKind string
+
+const (
+	PickupCoin      PickupKind = "coin"
+	PickupBomb      PickupKind = "bomb"
+	PickupKey       PickupKind = "key"
+	PickupRedHeart  PickupKind = "redHeart"
+	PickupSoulHeart PickupKind = "soulHeart"
+)
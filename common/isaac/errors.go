@@ -39,3 +39,13 @@ type NoSuchItemError struct {
 func (e NoSuchItemError) Error() string {
 	return e.Message
 }
+
+// ModDataCorruptedError is returned when the mod data file stays truncated or
+// unparsable after retrying, the previous data is kept.
+type ModDataCorruptedError struct {
+	Message string
+}
+
+func (e ModDataCorruptedError) Error() string {
+	return e.Message
+}
@@ -0,0 +1,111 @@
+package isaac
+
+import (
+	configModel "IsaacCoyote/common/config/model"
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"go.uber.org/zap"
+	"os"
+	"time"
+)
+
+const (
+	fetchAttempts   = 3
+	fetchRetryDelay = 20 * time.Millisecond
+)
+
+// fileTransport shares the save*.dat file Isaac reads with Isaac.LoadModData.
+// It is read as soon as it changes, polling is only a fallback.
+type fileTransport struct {
+	config *configModel.Isaac
+
+	modDataPath string
+	lastRawData []byte
+	watcher     *dataWatcher
+}
+
+func (t *fileTransport) Open(ctx context.Context) error {
+	modDataPath, err := locateModData(ctx, t.config)
+	if err != nil {
+		return err
+	}
+	zap.L().Debug("get mod data file", zap.String("modDataPath", modDataPath))
+	t.modDataPath = modDataPath
+	t.lastRawData = nil
+
+	if t.config.Polling {
+		return nil
+	}
+	watcher, err := newDataWatcher(modDataPath)
+	if err != nil {
+		zap.L().Warn("无法监听数据文件, 使用轮询", zap.Error(err))
+		return nil
+	}
+	t.watcher = watcher
+	go watcher.run(ctx)
+	return nil
+}
+
+func (t *fileTransport) Changed() <-chan struct{} {
+	if t.watcher == nil {
+		return nil
+	}
+	return t.watcher.changed
+}
+
+// Read skips data last read or written by us. Isaac writes the file in place,
+// so a partial read is retried before a ModDataCorruptedError is returned.
+func (t *fileTransport) Read() (ModData, bool, error) {
+	for attempt := 1; ; attempt++ {
+		rawData, err := os.ReadFile(t.modDataPath)
+		if err != nil {
+			return ModData{}, false, err
+		}
+		if bytes.Equal(rawData, t.lastRawData) {
+			return ModData{}, false, nil
+		}
+
+		var data ModData
+		err = json.Unmarshal(rawData, &data)
+		if err == nil {
+			t.lastRawData = rawData
+			return data, true, nil
+		}
+		if attempt == fetchAttempts {
+			return ModData{}, false, ModDataCorruptedError{
+				Message: fmt.Sprintf("Mod data is corrupted after %d reads (%d bytes): %s", attempt, len(rawData), err),
+			}
+		}
+		time.Sleep(fetchRetryDelay)
+	}
+}
+
+func (t *fileTransport) Write(data ModData) error {
+	jsonData, err := json.Marshal(data)
+	if err != nil {
+		return err
+	}
+	err = writeFileAtomic(t.modDataPath, jsonData, 0644)
+	if err != nil {
+		return err
+	}
+	t.lastRawData = jsonData
+	return nil
+}
+
+func (t *fileTransport) Close() error {
+	if t.watcher == nil {
+		return nil
+	}
+	err := t.watcher.Close()
+	t.watcher = nil
+	return err
+}
+
+func newFileTransport(config *configModel.Isaac) *fileTransport {
+	return &fileTransport{
+		config: config,
+	}
+}
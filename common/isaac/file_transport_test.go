@@ -0,0 +1,174 @@
+package isaac
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"math/rand/v2"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+const pollInterval = 256 * time.Millisecond
+
+// readLoop reads t like GameListener.Run does and reports the time of every new read.
+// The ticker only reads while t has no watcher.
+func readLoop(ctx context.Context, t *fileTransport, reads chan<- time.Time) {
+	ticker := time.NewTicker(pollInterval)
+	defer ticker.Stop()
+
+	changed := t.Changed()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-changed:
+		case <-ticker.C:
+			if changed != nil {
+				continue
+			}
+		}
+		_, ok, err := t.Read()
+		if err != nil || !ok {
+			continue
+		}
+		select {
+		case reads <- time.Now():
+		case <-ctx.Done():
+			return
+		}
+	}
+}
+
+// benchmarkReadLatency writes the mod data file in place like Isaac does and
+// measures how long it takes until the transport has read it.
+func benchmarkReadLatency(b *testing.B, watch bool) {
+	path := filepath.Join(b.TempDir(), "save1.dat")
+	err := os.WriteFile(path, []byte("{}"), 0644)
+	if err != nil {
+		b.Fatal(err)
+	}
+
+	transport := &fileTransport{modDataPath: path}
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	if watch {
+		watcher, err := newDataWatcher(path)
+		if err != nil {
+			b.Skip("fsnotify unavailable:", err)
+		}
+		defer watcher.Close()
+		transport.watcher = watcher
+		go watcher.run(ctx)
+	}
+
+	_, _, err = transport.Read()
+	if err != nil {
+		b.Fatal(err)
+	}
+	reads := make(chan time.Time, 1)
+	go readLoop(ctx, transport, reads)
+
+	// ns/op includes the random delay of polling, ms/read is the latency
+	var total time.Duration
+	b.ResetTimer()
+	for i := range b.N {
+		if !watch {
+			// writes land at a random point of the polling interval
+			time.Sleep(rand.N(pollInterval))
+		}
+		data, _ := json.Marshal(ModData{HostSession: int64(i + 1)})
+		written := time.Now()
+		err := os.WriteFile(path, data, 0644)
+		if err != nil {
+			b.Fatal(err)
+		}
+		select {
+		case read := <-reads:
+			total += read.Sub(written)
+		case <-time.After(5 * pollInterval):
+			b.Fatal("write was never read")
+		}
+	}
+	b.ReportMetric(float64(total.Microseconds())/float64(b.N)/1000, "ms/read")
+}
+
+// BenchmarkReadLatency compares the write to read latency of the mod data file
+// with fsnotify against polling every 256ms.
+func BenchmarkReadLatency(b *testing.B) {
+	b.Run("fsnotify", func(b *testing.B) {
+		benchmarkReadLatency(b, true)
+	})
+	b.Run("polling", func(b *testing.B) {
+		benchmarkReadLatency(b, false)
+	})
+}
+
+func TestReadCorrupted(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "save1.dat")
+	err := os.WriteFile(path, []byte(`{"send":[]}`), 0644)
+	if err != nil {
+		t.Fatal(err)
+	}
+	transport := &fileTransport{modDataPath: path}
+	_, _, err = transport.Read()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Isaac writes the file in place, a read may see it truncated or empty
+	for _, data := range []string{`{"send":[{"id":1,"type":"event","message":{"type":"PlayerHurtEvent","data":{"damage":`, `{"send":`, ``} {
+		err := os.WriteFile(path, []byte(data), 0644)
+		if err != nil {
+			t.Fatal(err)
+		}
+		_, changed, err := transport.Read()
+		var corruptedErr ModDataCorruptedError
+		if !errors.As(err, &corruptedErr) {
+			t.Fatalf("Read(%q) error = %v, want ModDataCorruptedError", data, err)
+		}
+		if changed {
+			t.Fatalf("Read(%q) reported a change", data)
+		}
+	}
+}
+
+func TestWriteReplacesFile(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "save1.dat")
+	err := os.WriteFile(path, []byte(`{"send":[],"receive":[],"modConfig":{"time":5}}`), 0644)
+	if err != nil {
+		t.Fatal(err)
+	}
+	transport := &fileTransport{modDataPath: path}
+
+	data := ModData{HostSession: 42, HostAck: 3, ModConfig: json.RawMessage(`{"time":5}`)}
+	err = transport.Write(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	raw, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want, _ := json.Marshal(data)
+	if !bytes.Equal(raw, want) {
+		t.Fatalf("file = %s, want %s", raw, want)
+	}
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(entries) != 1 {
+		t.Fatalf("temp files left next to the data file: %v", entries)
+	}
+	// our own write is not read back as new data
+	_, changed, err := transport.Read()
+	if err != nil || changed {
+		t.Fatalf("Read after Write = %v, %v, want no change", changed, err)
+	}
+}
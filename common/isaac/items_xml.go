@@ -0,0 +1,193 @@
+package isaac
+
+import (
+	"encoding/xml"
+	"fmt"
+	"os"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+// xmlEntry is one element of items.xml or pocketitems.xml, only the attributes
+// the database needs are read.
+type xmlEntry struct {
+	XMLName xml.Name
+	ID      string `xml:"id,attr"`
+	Name    string `xml:"name,attr"`
+	Type    string `xml:"type,attr"`
+	Quality string `xml:"quality,attr"`
+	Tags    string `xml:"tags,attr"`
+	Class   string `xml:"class,attr"`
+}
+
+type xmlEntries struct {
+	Entries []xmlEntry `xml:",any"`
+}
+
+type xmlItemPools struct {
+	Pools []struct {
+		Name  string `xml:"Name,attr"`
+		Items []struct {
+			ID int `xml:"Id,attr"`
+		} `xml:"Item"`
+	} `xml:"Pool"`
+}
+
+func readXML(path string, v any) error {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return err
+	}
+	err = xml.Unmarshal(data, v)
+	if err != nil {
+		return fmt.Errorf("%s: %w", path, err)
+	}
+	return nil
+}
+
+func (e xmlEntry) id(path string) (int, error) {
+	id, err := strconv.Atoi(e.ID)
+	if err != nil {
+		return 0, fmt.Errorf("%s: <%s name=%q> has invalid id %q", path, e.XMLName.Local, e.Name, e.ID)
+	}
+	return id, nil
+}
+
+// mergeName keeps current over localization keys such as #SAD_ONION_NAME,
+// which newer versions of the game store instead of names.
+func mergeName(current string, name string) string {
+	if name == "" || (current != "" && strings.HasPrefix(name, "#")) {
+		return current
+	}
+	return name
+}
+
+// mergeItem applies the attributes present in the entry to item.
+func (e xmlEntry) mergeItem(item ItemDetail) ItemDetail {
+	item.Type = e.XMLName.Local
+	item.Name = mergeName(item.Name, e.Name)
+	if quality, err := strconv.Atoi(e.Quality); err == nil {
+		item.Quality = quality
+	}
+	if e.Tags != "" {
+		item.Tags = strings.Fields(e.Tags)
+	}
+	return item
+}
+
+func isCollectibleElement(name string) bool {
+	return name == "passive" || name == "active" || name == "familiar"
+}
+
+// importItemsXML merges the collectibles and trinkets of the game's items.xml into db.
+func importItemsXML(path string, db *itemDatabase) error {
+	var items xmlEntries
+	err := readXML(path, &items)
+	if err != nil {
+		return err
+	}
+
+	for _, entry := range items.Entries {
+		switch {
+		case isCollectibleElement(entry.XMLName.Local):
+			id, err := entry.id(path)
+			if err != nil {
+				return err
+			}
+			item := db.collectibles[id]
+			item.ID = id
+			db.collectibles[id] = entry.mergeItem(item)
+		case entry.XMLName.Local == "trinket":
+			id, err := entry.id(path)
+			if err != nil {
+				return err
+			}
+			trinket := db.trinkets[id]
+			trinket.ID = id
+			trinket.Name = mergeName(trinket.Name, entry.Name)
+			if entry.Tags != "" {
+				trinket.Tags = strings.Fields(entry.Tags)
+			}
+			db.trinkets[id] = trinket
+		}
+	}
+	return nil
+}
+
+// importItemPoolsXML records the pools of itempools.xml on the collectibles in db.
+func importItemPoolsXML(path string, db *itemDatabase) error {
+	var pools xmlItemPools
+	err := readXML(path, &pools)
+	if err != nil {
+		return err
+	}
+
+	for _, pool := range pools.Pools {
+		for _, poolItem := range pool.Items {
+			item, ok := db.collectibles[poolItem.ID]
+			if !ok || slices.Contains(item.Pools, pool.Name) {
+				continue
+			}
+			item.Pools = append(slices.Clone(item.Pools), pool.Name)
+			db.collectibles[poolItem.ID] = item
+		}
+	}
+	return nil
+}
+
+// importPocketItemsXML merges the cards, runes and pill effects of pocketitems.xml into db.
+func importPocketItemsXML(path string, db *itemDatabase) error {
+	var pocketItems xmlEntries
+	err := readXML(path, &pocketItems)
+	if err != nil {
+		return err
+	}
+
+	for _, entry := range pocketItems.Entries {
+		switch entry.XMLName.Local {
+		case "card", "rune":
+			id, err := entry.id(path)
+			if err != nil {
+				return err
+			}
+			card := db.cards[id]
+			card.ID = id
+			card.Name = mergeName(card.Name, entry.Name)
+			card.Type = entry.Type
+			if card.Type == "" {
+				card.Type = entry.XMLName.Local
+			}
+			db.cards[id] = card
+		case "pilleffect":
+			id, err := entry.id(path)
+			if err != nil {
+				return err
+			}
+			effect := db.pillEffects[id]
+			effect.ID = id
+			effect.Name = mergeName(effect.Name, entry.Name)
+			effect.Class = entry.Class
+			db.pillEffects[id] = effect
+		}
+	}
+	return nil
+}
+
+// importModItemsXML adds the collectibles of a mod's content/items.xml to db,
+// they have no fixed IDs and are looked up by name.
+func importModItemsXML(path string, db *itemDatabase) error {
+	var items xmlEntries
+	err := readXML(path, &items)
+	if err != nil {
+		return err
+	}
+
+	for _, entry := range items.Entries {
+		if !isCollectibleElement(entry.XMLName.Local) || entry.Name == "" {
+			continue
+		}
+		db.moddedItems[entry.Name] = entry.mergeItem(db.moddedItems[entry.Name])
+	}
+	return nil
+}
@@ -1,41 +1,82 @@
 package isaac
 
 import (
-	"encoding/json"
+	configModel "IsaacCoyote/common/config/model"
+	"context"
+	"errors"
 	"go.uber.org/zap"
-	"os"
+	"sync"
+	"sync/atomic"
 	"time"
 )
 
 type CallbackFunc func(callbackData interface{})
 
 type GameListener struct {
-	modDataPath string
+	config    *configModel.Isaac
+	transport Transport
 
 	ResourceManager *ResourceManager
+	callbackLock    sync.RWMutex
 	callbacks       map[Event][]CallbackFunc
 
 	currModData          ModData
+	corruptedReads       atomic.Int64
 	lastRecHeartbeatTime time.Time
 	IsConnected          bool
-	msgBuffer            []ModMessage
+
+	// messages to the mod are resent until the mod acks them, messages from
+	// the mod are processed once by ID and acked with hostAck
+	msgLock     sync.Mutex
+	pendingMsgs []ModMessage
+	nextMsgID   int64
+	hostSession int64
+	modSession  int64
+	hostAck     int64
+	ackPending  bool
+
+	runLock   sync.Mutex
+	cancelRun context.CancelFunc
+	runDone   chan struct{}
 }
 
-func (g *GameListener) Run() error {
+// Run listens to the mod until the connection is lost, ctx is cancelled or Shutdown is called.
+func (g *GameListener) Run(ctx context.Context) error {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	g.runLock.Lock()
+	g.cancelRun = cancel
+	g.runDone = make(chan struct{})
+	runDone := g.runDone
+	g.runLock.Unlock()
+	defer close(runDone)
+
 	err := g.ResourceManager.LoadResources()
 	if err != nil {
 		zap.L().Error("请检查资源文件", zap.Error(err))
 		return err
 	}
 
-	pid := waitForProcess("isaac-ng.exe")
-	modDataPath, err := getModDataFile(pid)
+	g.transport = newTransport(g.config)
+	err = g.transport.Open(ctx)
+	if err != nil {
+		if ctx.Err() != nil {
+			return err
+		}
+		zap.L().Error("连接 mod 失败", zap.Error(err))
+		return err
+	}
+	defer g.transport.Close()
+	err = g.loadModConfig(ctx)
 	if err != nil {
-		zap.L().Error("获取数据文件失败", zap.Error(err))
+		if ctx.Err() != nil {
+			return err
+		}
+		zap.L().Error("读取数据文件失败", zap.Error(err))
 		return err
 	}
-	zap.L().Debug("get mod data file", zap.String("modDataPath", modDataPath))
-	g.modDataPath = modDataPath
+	g.resetSession()
 
 	g.AddMessage(ModMessage{
 		Type: ConnectMsg,
@@ -49,53 +90,183 @@ func (g *GameListener) Run() error {
 	g.lastRecHeartbeatTime = time.Now()
 	g.triggerCallback(ModInitEvent, nil)
 
-	var lastHeartbeatTime time.Time
+	// data is read as soon as the transport signals a change, polling is only a fallback
+	changed := g.transport.Changed()
+	var lastHeartbeatTime, lastChangeTime time.Time
 	ticker := time.NewTicker(256 * time.Millisecond)
 	defer ticker.Stop()
 
 	for g.IsConnected {
-		<-ticker.C
-		err = g.FetchData()
-		if err != nil {
-			zap.L().Error("读取数据失败", zap.Error(err))
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case _, ok := <-changed:
+			if !ok {
+				zap.L().Warn("监听已停止, 使用轮询")
+				changed = nil
+				continue
+			}
+			lastChangeTime = time.Now()
+			g.readModData()
 			continue
+		case <-ticker.C:
+		}
+
+		// notifications may silently never arrive, e.g. on network drives
+		if changed == nil || time.Since(lastChangeTime) > time.Second {
+			g.readModData()
 		}
-		g.statistics()
 
 		if time.Since(lastHeartbeatTime) > 2*time.Second {
 			g.addHeartbeatMsg()
 			lastHeartbeatTime = time.Now()
 		}
+		g.flush()
+		g.checkConnection()
+	}
+	return nil
+}
 
-		if len(g.msgBuffer) != 0 {
-			err = g.Write()
-			if err != nil {
-				continue
+// loadModConfig reads the data before our first write replaces it, so the
+// modConfig of the mod is written back. A corrupted read is retried until Isaac
+// has finished writing the file.
+func (g *GameListener) loadModConfig(ctx context.Context) error {
+	for {
+		data, changed, err := g.transport.Read()
+		var corruptedErr ModDataCorruptedError
+		if !errors.As(err, &corruptedErr) {
+			if changed {
+				g.currModData = data
 			}
+			return err
+		}
+		g.corruptedReads.Add(1)
+		zap.L().Warn("数据损坏, 等待 mod 重新写入", zap.Int64("corruptedReads", g.CorruptedReads()), zap.Error(err))
+
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(256 * time.Millisecond):
 		}
-		g.checkConnection()
 	}
-	return nil
+}
+
+// readModData handles new messages from the mod and answers right away.
+func (g *GameListener) readModData() {
+	data, changed, err := g.transport.Read()
+	var corruptedErr ModDataCorruptedError
+	if errors.As(err, &corruptedErr) {
+		g.corruptedReads.Add(1)
+		zap.L().Warn("数据损坏, 已保留上次的数据", zap.Int64("corruptedReads", g.CorruptedReads()), zap.Error(err))
+		return
+	}
+	if err != nil {
+		zap.L().Error("读取数据失败", zap.Error(err))
+		return
+	}
+	if !changed {
+		return
+	}
+	g.currModData = data
+	g.statistics()
+	g.flush()
+}
+
+func (g *GameListener) flush() {
+	if !g.needsWrite() {
+		return
+	}
+	err := g.Write()
+	if err != nil {
+		zap.L().Error("写入数据失败", zap.Error(err))
+	}
+}
+
+// Shutdown stops a running Run and waits for it to return.
+func (g *GameListener) Shutdown(ctx context.Context) error {
+	g.runLock.Lock()
+	cancel, runDone := g.cancelRun, g.runDone
+	g.runLock.Unlock()
+
+	if cancel == nil {
+		return nil
+	}
+	cancel()
+
+	select {
+	case <-runDone:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// resetSession starts a new host session, the mod drops whatever it has not
+// delivered yet once it receives our connect message.
+func (g *GameListener) resetSession() {
+	g.msgLock.Lock()
+	defer g.msgLock.Unlock()
+
+	g.pendingMsgs = nil
+	g.nextMsgID = 1
+	g.hostSession = time.Now().UnixMilli()
+	g.modSession = 0
+	g.hostAck = 0
+	g.ackPending = false
+}
+
+// receiveMessages returns the messages from the mod that were not processed yet
+// and prunes our messages the mod has acked.
+func (g *GameListener) receiveMessages() []ModMessage {
+	g.msgLock.Lock()
+	defer g.msgLock.Unlock()
+
+	data := g.currModData
+	// written by the mod before it saw our connect message
+	if data.HostSession != g.hostSession {
+		return nil
+	}
+
+	if data.ModSession != g.modSession {
+		g.modSession = data.ModSession
+		g.hostAck = 0
+	}
+
+	acked := 0
+	for acked < len(g.pendingMsgs) && g.pendingMsgs[acked].ID <= data.ModAck {
+		acked++
+	}
+	g.pendingMsgs = g.pendingMsgs[acked:]
+
+	var messages []ModMessage
+	for _, message := range data.Send {
+		if message.ID <= g.hostAck {
+			continue
+		}
+		messages = append(messages, message)
+		g.hostAck = message.ID
+		g.ackPending = true
+	}
+	return messages
 }
 
 func (g *GameListener) statistics() {
 	var eventList []EventMessageData
-	var deathFlag bool
-	for _, message := range g.currModData.Send {
+	// the hurt that killed a player is not reported separately
+	deadPlayers := make(map[int]bool)
+	for _, message := range g.receiveMessages() {
 		switch message.Type {
 		case EventMsg:
 			eventData := message.Message.(EventMessageData)
-			if eventData.Type == PlayerDeathEvent.String() {
-				deathFlag = true
+			if deathData, ok := eventData.Data.(PlayerDeathEventData); ok {
+				deadPlayers[deathData.PlayerIndex] = true
 			}
 			eventList = append(eventList, message.Message.(EventMessageData))
 		case HeartbeatMsg:
 			g.lastRecHeartbeatTime = time.Now()
-		default:
-			return
 		}
 	}
-	g.dispatchEvent(eventList, deathFlag)
+	g.dispatchEvent(eventList, deadPlayers)
 }
 
 func (g *GameListener) checkConnection() {
@@ -106,22 +277,44 @@ func (g *GameListener) checkConnection() {
 	}
 }
 
+// Write sends every message the mod has not acked yet together with our ack.
 func (g *GameListener) Write() error {
+	g.msgLock.Lock()
+	receive := make([]ModMessage, len(g.pendingMsgs))
+	copy(receive, g.pendingMsgs)
 	data := ModData{
-		Send:      make([]ModMessage, 0),
-		Receive:   g.msgBuffer,
-		ModConfig: g.currModData.ModConfig,
+		Send:        make([]ModMessage, 0),
+		Receive:     receive,
+		ModConfig:   g.currModData.ModConfig,
+		HostSession: g.hostSession,
+		ModSession:  g.modSession,
+		HostAck:     g.hostAck,
 	}
-	jsonData, err := json.Marshal(data)
-	if err != nil {
-		return err
-	}
-	g.msgBuffer = make([]ModMessage, 0)
-	return os.WriteFile(g.modDataPath, jsonData, 0644)
+	g.ackPending = false
+	g.msgLock.Unlock()
+
+	return g.transport.Write(data)
+}
+
+// needsWrite reports whether there are unacked messages or an ack to send.
+func (g *GameListener) needsWrite() bool {
+	g.msgLock.Lock()
+	defer g.msgLock.Unlock()
+
+	return len(g.pendingMsgs) != 0 || g.ackPending
 }
 
 func (g *GameListener) AddMessage(message ModMessage) {
-	g.msgBuffer = append(g.msgBuffer, message)
+	g.msgLock.Lock()
+	defer g.msgLock.Unlock()
+
+	g.addMessageLocked(message)
+}
+
+func (g *GameListener) addMessageLocked(message ModMessage) {
+	message.ID = g.nextMsgID
+	g.nextMsgID++
+	g.pendingMsgs = append(g.pendingMsgs, message)
 }
 
 func (g *GameListener) addHeartbeatMsg() {
@@ -130,8 +323,20 @@ func (g *GameListener) addHeartbeatMsg() {
 	})
 }
 
+// AddUpdateIndicatorMsg supersedes any indicator update the mod has not acked yet.
 func (g *GameListener) AddUpdateIndicatorMsg(strengthA int, strengthB int) {
-	g.AddMessage(ModMessage{
+	g.msgLock.Lock()
+	defer g.msgLock.Unlock()
+
+	pendingMsgs := g.pendingMsgs[:0]
+	for _, message := range g.pendingMsgs {
+		if message.Type != UpdateIndicatorMsg {
+			pendingMsgs = append(pendingMsgs, message)
+		}
+	}
+	g.pendingMsgs = pendingMsgs
+
+	g.addMessageLocked(ModMessage{
 		Type: UpdateIndicatorMsg,
 		Message: UpdateIndicatorData{
 			StrengthA: strengthA,
@@ -140,32 +345,26 @@ func (g *GameListener) AddUpdateIndicatorMsg(strengthA int, strengthB int) {
 	})
 }
 
-func (g *GameListener) FetchData() error {
-	rawData, err := os.ReadFile(g.modDataPath)
-	if err != nil {
-		return err
-	}
-
-	var data ModData
-	_ = json.Unmarshal(rawData, &data)
-	g.currModData = data
-	return nil
+// CorruptedReads is how many times the data from the mod was unreadable.
+func (g *GameListener) CorruptedReads() int64 {
+	return g.corruptedReads.Load()
 }
 
-func (g *GameListener) dispatchEvent(eventList []EventMessageData, deathFlag bool) {
+func (g *GameListener) dispatchEvent(eventList []EventMessageData, deadPlayers map[int]bool) {
 	for _, eventData := range eventList {
 		zap.L().Debug("event", zap.String("event", eventData.Type))
 		switch eventData.Type {
 		case PlayerHurtEvent.String():
-			if !deathFlag {
-				g.triggerCallback(PlayerHurtEvent, eventData.Data.(PlayerHurtEventData))
+			hurtData := eventData.Data.(PlayerHurtEventData)
+			if !deadPlayers[hurtData.PlayerIndex] {
+				g.triggerCallback(PlayerHurtEvent, hurtData)
 			}
 			break
 		case PlayerInfoUpdateEvent.String():
 			g.triggerCallback(PlayerInfoUpdateEvent, eventData.Data.(PlayerInfoUpdateEventData))
 			break
 		case PlayerDeathEvent.String():
-			g.triggerCallback(PlayerDeathEvent, nil)
+			g.triggerCallback(PlayerDeathEvent, eventData.Data.(PlayerDeathEventData))
 			break
 		case ManualRestartEvent.String():
 			g.triggerCallback(ManualRestartEvent, nil)
@@ -179,25 +378,40 @@ func (g *GameListener) dispatchEvent(eventList []EventMessageData, deathFlag boo
 		case GameEndEvent.String():
 			g.triggerCallback(GameEndEvent, nil)
 			break
+		case NewCollectibleEvent.String(), RoomClearEvent.String(), BossKilledEvent.String(),
+			NewFloorEvent.String(), ActiveItemUsedEvent.String(), PillUsedEvent.String(),
+			CardUsedEvent.String(), PickupCollectedEvent.String(), DevilDealTakenEvent.String():
+			// payload is already typed by EventMessageData.UnmarshalJSON
+			g.triggerCallback(Event(eventData.Type), eventData.Data)
 		}
 	}
 }
 
+// triggerCallback runs the callbacks of eventType on the calling goroutine, so the
+// events of a batch are handled one after another in the order the mod sent them.
 func (g *GameListener) triggerCallback(eventType Event, callbackData interface{}) {
-	if g.callbacks == nil {
-		return
-	}
-	callbacks, ok := g.callbacks[eventType]
-	if !ok || len(callbacks) == 0 {
-		return
-	}
+	g.callbackLock.RLock()
+	callbacks := g.callbacks[eventType]
+	g.callbackLock.RUnlock()
+
 	for _, callback := range callbacks {
-		go callback(callbackData)
+		callback(callbackData)
 	}
-	return
 }
 
 func (g *GameListener) RegisterCallback(eventType Event, callback CallbackFunc) error {
+	if !eventType.IsValid() {
+		return InvalidEventTypeError{
+			Message: "Invalid event type: " + eventType.String(),
+		}
+	}
+	if callback == nil {
+		return InvalidCallbackError{
+			Message: "Callback is nil",
+		}
+	}
+	g.callbackLock.Lock()
+	defer g.callbackLock.Unlock()
 	if g.callbacks == nil {
 		g.callbacks = make(map[Event][]CallbackFunc)
 	}
@@ -205,8 +419,19 @@ func (g *GameListener) RegisterCallback(eventType Event, callback CallbackFunc)
 	return nil
 }
 
-func NewGameListener() *GameListener {
+// GetItemByID looks the collectible up in the loaded resources.
+func (g *GameListener) GetItemByID(itemID int) (ItemDetail, error) {
+	return g.ResourceManager.GetItemByID(itemID)
+}
+
+// GetItemByName looks the collectible up by name, modded items can only be found this way.
+func (g *GameListener) GetItemByName(itemName string) (ItemDetail, error) {
+	return g.ResourceManager.GetItemByName(itemName)
+}
+
+func NewGameListener(config *configModel.Isaac) *GameListener {
 	return &GameListener{
-		ResourceManager: NewResourceManager(),
+		config:          config,
+		ResourceManager: NewResourceManager(&config.Resources),
 	}
 }
@@ -0,0 +1,276 @@
+package isaac
+
+import (
+	configModel "IsaacCoyote/common/config/model"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+// testListener is a GameListener on a data file in a temporary directory,
+// the test plays the mod by writing the file.
+type testListener struct {
+	t        *testing.T
+	listener *GameListener
+	path     string
+	// hurts are the damages of the dispatched PlayerHurtEvents
+	hurts []float64
+}
+
+func newTestListener(t *testing.T, initial string) *testListener {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "save1.dat")
+	if err := os.WriteFile(path, []byte(initial), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	l := &testListener{
+		t:        t,
+		listener: NewGameListener(&configModel.Isaac{}),
+		path:     path,
+	}
+	l.listener.transport = &fileTransport{modDataPath: path}
+	err := l.listener.RegisterCallback(PlayerHurtEvent, func(callbackData interface{}) {
+		l.hurts = append(l.hurts, callbackData.(PlayerHurtEventData).Damage)
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return l
+}
+
+// modWrites writes the file like the mod, every id is a PlayerHurtEvent with
+// that id as damage.
+func (l *testListener) modWrites(hostSession int64, modSession int64, modAck int64, ids ...int64) {
+	l.t.Helper()
+	send := make([]string, 0, len(ids))
+	for _, id := range ids {
+		send = append(send, fmt.Sprintf(`{"id":%d,"type":"event","message":{"type":"PlayerHurtEvent","data":{"playerIndex":0,"damage":%d}}}`, id, id))
+	}
+	data := fmt.Sprintf(`{"send":[%s],"receive":[],"modConfig":{"IndicatorSize":10,"time":5},"hostSession":%d,"modSession":%d,"hostAck":0,"modAck":%d}`,
+		strings.Join(send, ","), hostSession, modSession, modAck)
+	if err := os.WriteFile(l.path, []byte(data), 0644); err != nil {
+		l.t.Fatal(err)
+	}
+}
+
+// hostData is what the listener last wrote.
+func (l *testListener) hostData() ModData {
+	l.t.Helper()
+	raw, err := os.ReadFile(l.path)
+	if err != nil {
+		l.t.Fatal(err)
+	}
+	var data ModData
+	if err := json.Unmarshal(raw, &data); err != nil {
+		l.t.Fatalf("%v: %s", err, raw)
+	}
+	return data
+}
+
+// takeHurts returns the hurts dispatched since the last call.
+func (l *testListener) takeHurts() []float64 {
+	hurts := l.hurts
+	l.hurts = nil
+	return hurts
+}
+
+func TestModDataCorruptedKeepsPreviousData(t *testing.T) {
+	l := newTestListener(t, "{}")
+	l.listener.resetSession()
+	host := l.listener.hostSession
+
+	l.modWrites(host, 1, 0, 1)
+	l.listener.readModData()
+	if got := l.takeHurts(); len(got) != 1 {
+		t.Fatalf("hurts = %v, want [1]", got)
+	}
+	previous := l.listener.currModData
+
+	for i, corrupted := range []string{`{"send":[{"id":2,"type":"ev`, ``, `{"send":[]`} {
+		err := os.WriteFile(l.path, []byte(corrupted), 0644)
+		if err != nil {
+			t.Fatal(err)
+		}
+		l.listener.readModData()
+		if got := l.listener.CorruptedReads(); got != int64(i+1) {
+			t.Fatalf("CorruptedReads = %d, want %d", got, i+1)
+		}
+		if l.listener.currModData.ModSession != previous.ModSession || len(l.listener.currModData.Send) != len(previous.Send) {
+			t.Fatalf("data after a corrupted read = %+v, want %+v", l.listener.currModData, previous)
+		}
+	}
+
+	l.modWrites(host, 1, 0, 1, 2)
+	l.listener.readModData()
+	if got := l.takeHurts(); len(got) != 1 || got[0] != 2 {
+		t.Fatalf("hurts = %v, want [2]", got)
+	}
+}
+
+func TestConnectKeepsModConfig(t *testing.T) {
+	l := newTestListener(t, `{"send":[],"receive":[],"modConfig":{"IndicatorSize":10,"time":5}}`)
+	l.listener.config.SaveFile = l.path
+	l.listener.config.Polling = true
+
+	done := make(chan error, 1)
+	go func() {
+		done <- l.listener.Run(t.Context())
+	}()
+	defer func() {
+		if err := l.listener.Shutdown(t.Context()); err != nil {
+			t.Error(err)
+		}
+		<-done
+	}()
+
+	deadline := time.Now().Add(5 * time.Second)
+	var data ModData
+	for data.HostSession == 0 {
+		if time.Now().After(deadline) {
+			t.Fatal("timed out waiting for the connect message")
+		}
+		time.Sleep(time.Millisecond)
+		raw, _ := os.ReadFile(l.path)
+		data = ModData{}
+		_ = json.Unmarshal(raw, &data)
+	}
+	if len(data.Receive) == 0 || data.Receive[0].Type != ConnectMsg {
+		t.Fatalf("receive = %+v, want the connect message", data.Receive)
+	}
+	if got := string(data.ModConfig); got != `{"IndicatorSize":10,"time":5}` {
+		t.Fatalf("modConfig = %s", got)
+	}
+}
+
+func TestLoadModConfigWaitsForValidData(t *testing.T) {
+	l := newTestListener(t, `{"send":[],"modConf`)
+
+	done := make(chan error, 1)
+	go func() {
+		done <- l.listener.loadModConfig(t.Context())
+	}()
+	for l.listener.CorruptedReads() == 0 {
+		select {
+		case err := <-done:
+			t.Fatalf("loadModConfig returned %v on corrupted data", err)
+		case <-time.After(time.Millisecond):
+		}
+	}
+	l.modWrites(0, 1, 0)
+	if err := <-done; err != nil {
+		t.Fatal(err)
+	}
+	if got := string(l.listener.currModData.ModConfig); got != `{"IndicatorSize":10,"time":5}` {
+		t.Fatalf("modConfig = %s", got)
+	}
+}
+
+func TestAckDispatchesEveryEventOnce(t *testing.T) {
+	l := newTestListener(t, "{}")
+	l.listener.resetSession()
+	host := l.listener.hostSession
+	l.listener.AddMessage(ModMessage{Type: HeartbeatMsg})
+
+	l.modWrites(host, 1, 0, 1, 2)
+	l.listener.readModData()
+	if got := l.takeHurts(); len(got) != 2 {
+		t.Fatalf("hurts = %v, want [1 2]", got)
+	}
+	if got := l.hostData().HostAck; got != 2 {
+		t.Fatalf("hostAck = %d, want 2", got)
+	}
+
+	// the mod added 3 between our read and our write, it has not seen our ack yet
+	l.modWrites(host, 1, 0, 1, 2, 3)
+	l.listener.readModData()
+	if got := l.takeHurts(); len(got) != 1 || got[0] != 3 {
+		t.Fatalf("hurts = %v, want [3]", got)
+	}
+	if got := l.hostData().HostAck; got != 3 {
+		t.Fatalf("hostAck = %d, want 3", got)
+	}
+
+	// a re-read of acked IDs, the mod only acked our heartbeat
+	l.modWrites(host, 1, 1, 2, 3)
+	l.listener.readModData()
+	if got := l.takeHurts(); len(got) != 0 {
+		t.Fatalf("hurts = %v, want none", got)
+	}
+	l.listener.msgLock.Lock()
+	pending := len(l.listener.pendingMsgs)
+	l.listener.msgLock.Unlock()
+	if pending != 0 {
+		t.Fatalf("%d messages pending after the mod acked them", pending)
+	}
+}
+
+func TestAckModSessionReset(t *testing.T) {
+	l := newTestListener(t, "{}")
+	l.listener.resetSession()
+	host := l.listener.hostSession
+
+	l.modWrites(host, 1, 0, 1, 2, 3)
+	l.listener.readModData()
+	l.takeHurts()
+
+	// the restarted mod counts from 1 again
+	l.modWrites(host, 2, 0, 1)
+	l.listener.readModData()
+	if got := l.takeHurts(); len(got) != 1 || got[0] != 1 {
+		t.Fatalf("hurts = %v, want [1]", got)
+	}
+	data := l.hostData()
+	if data.ModSession != 2 || data.HostAck != 1 {
+		t.Fatalf("modSession = %d, hostAck = %d, want 2 and 1", data.ModSession, data.HostAck)
+	}
+}
+
+func TestAckHostSessionReset(t *testing.T) {
+	l := newTestListener(t, "{}")
+	l.listener.resetSession()
+	oldHost := l.listener.hostSession
+
+	l.modWrites(oldHost, 1, 0, 1, 2)
+	l.listener.readModData()
+	l.takeHurts()
+	l.listener.AddMessage(ModMessage{Type: HeartbeatMsg})
+
+	// the listener restarts, the session has to differ from the old one
+	time.Sleep(2 * time.Millisecond)
+	l.listener.resetSession()
+	host := l.listener.hostSession
+	if host == oldHost {
+		t.Fatal("resetSession kept the host session")
+	}
+	l.listener.AddMessage(ModMessage{Type: ConnectMsg})
+	if err := l.listener.Write(); err != nil {
+		t.Fatal(err)
+	}
+	data := l.hostData()
+	if len(data.Receive) != 1 || data.Receive[0].ID != 1 || data.Receive[0].Type != ConnectMsg {
+		t.Fatalf("receive = %+v, want only the connect message with ID 1", data.Receive)
+	}
+
+	// written before the mod saw our connect message
+	l.modWrites(oldHost, 1, 0, 1, 2, 3)
+	l.listener.readModData()
+	if got := l.takeHurts(); len(got) != 0 {
+		t.Fatalf("hurts of the old host session = %v, want none", got)
+	}
+
+	// the mod dropped what it had not delivered and acks our connect message
+	l.modWrites(host, 1, 1, 4)
+	l.listener.readModData()
+	if got := l.takeHurts(); len(got) != 1 || got[0] != 4 {
+		t.Fatalf("hurts = %v, want [4]", got)
+	}
+	data = l.hostData()
+	if data.HostAck != 4 || len(data.Receive) != 0 {
+		t.Fatalf("hostAck = %d, receive = %+v, want 4 and nothing pending", data.HostAck, data.Receive)
+	}
+}
@@ -1,14 +1,29 @@
 package isaac
 
-import "encoding/json"
+import (
+	"bytes"
+	"encoding/json"
+)
 
 type ModData struct {
-	Send      []ModMessage `json:"send"`
-	Receive   []ModMessage `json:"receive"`
-	ModConfig interface{}  `json:"modConfig"`
+	Send    []ModMessage `json:"send"`
+	Receive []ModMessage `json:"receive"`
+	// ModConfig belongs to the mod and is written back untouched
+	ModConfig json.RawMessage `json:"modConfig,omitempty"`
+
+	// HostSession and ModSession identify the running listener and mod, each side
+	// echoes the session of the other so stale acks are never applied
+	HostSession int64 `json:"hostSession"`
+	ModSession  int64 `json:"modSession"`
+	// HostAck is the last message ID from the mod we processed,
+	// ModAck the last message ID from us the mod processed
+	HostAck int64 `json:"hostAck"`
+	ModAck  int64 `json:"modAck"`
 }
 
 type ModMessage struct {
+	// ID increases by one per message within a session
+	ID         int64       `json:"id"`
 	Type       string      `json:"type"`
 	Message    interface{} `json:"message"`
 	FrameCount int64       `json:"frameCount"`
@@ -57,38 +72,72 @@ func (e *EventMessageData) UnmarshalJSON(data []byte) error {
 	}
 	e.Type = eventMsgData.Type
 
-	switch eventMsgData.Type {
-	case "PlayerHurtEvent":
-		var playerHurtEventData PlayerHurtEventData
-		if err := json.Unmarshal(eventMsgData.Data, &playerHurtEventData); err != nil {
-			return err
-		}
-		e.Data = playerHurtEventData
-	case "NewCollectibleEvent":
-		var newCollectibleEventData NewCollectibleEventData
-		if err := json.Unmarshal(eventMsgData.Data, &newCollectibleEventData); err != nil {
-			return err
-		}
-		e.Data = newCollectibleEventData
-	case "PlayerInfoUpdateEvent":
-		var playerInfoUpdateEventData PlayerInfoUpdateEventData
-		if err := json.Unmarshal(eventMsgData.Data, &playerInfoUpdateEventData); err != nil {
-			return err
-		}
-		e.Data = playerInfoUpdateEventData
-	case "GameStartEvent":
-		var gameStartEventData GameStartEventData
-		if err := json.Unmarshal(eventMsgData.Data, &gameStartEventData); err != nil {
-			return err
-		}
-		e.Data = gameStartEventData
-	default:
+	decode, ok := eventDataDecoders[Event(eventMsgData.Type)]
+	if !ok {
 		e.Data = nil
+		return nil
 	}
-	return nil
+	var err error
+	e.Data, err = decode(eventMsgData.Data)
+	return err
+}
+
+// eventDataDecoders decode the payload of the events that have one.
+var eventDataDecoders = map[Event]func(json.RawMessage) (any, error){
+	PlayerHurtEvent:       unmarshalEventData[PlayerHurtEventData],
+	PlayerDeathEvent:      unmarshalEventData[PlayerDeathEventData],
+	NewCollectibleEvent:   unmarshalEventData[NewCollectibleEventData],
+	PlayerInfoUpdateEvent: unmarshalEventData[PlayerInfoUpdateEventData],
+	GameStartEvent:        unmarshalEventData[GameStartEventData],
+	RoomClearEvent:        unmarshalEventData[RoomClearEventData],
+	BossKilledEvent:       unmarshalEventData[BossKilledEventData],
+	NewFloorEvent:         unmarshalEventData[NewFloorEventData],
+	ActiveItemUsedEvent:   unmarshalEventData[ActiveItemUsedEventData],
+	PillUsedEvent:         unmarshalEventData[PillUsedEventData],
+	CardUsedEvent:         unmarshalEventData[CardUsedEventData],
+	PickupCollectedEvent:  unmarshalEventData[PickupCollectedEventData],
+	DevilDealTakenEvent:   unmarshalEventData[DevilDealTakenEventData],
+
+	PlayerTransformationEvent: unmarshalEventData[PlayerTransformationEventData],
+}
+
+// NewEventData returns the zero payload of event, nil for events without one.
+func NewEventData(event Event) any {
+	decode, ok := eventDataDecoders[event]
+	if !ok {
+		return nil
+	}
+	data, _ := decode(json.RawMessage("{}"))
+	return data
+}
+
+func unmarshalEventData[T any](data json.RawMessage) (any, error) {
+	var eventData T
+	err := json.Unmarshal(data, &eventData)
+	return eventData, err
+}
+
+// PlayerRef identifies the player of an event in local co-op. Players are
+// numbered like the game HUD, twins such as Jacob & Esau share one number.
+type PlayerRef struct {
+	// PlayerIndex is 0 for player 1
+	PlayerIndex     int `json:"playerIndex"`
+	ControllerIndex int `json:"controllerIndex"`
+	// PlayerType is the PlayerType enum of the character
+	PlayerType int `json:"playerType"`
+}
+
+func (p PlayerRef) Player() PlayerRef {
+	return p
+}
+
+// PlayerEvent is implemented by the payloads of events that belong to a player.
+type PlayerEvent interface {
+	Player() PlayerRef
 }
 
 type PlayerHurtEventData struct {
+	PlayerRef
 	PlayerName string  `json:"playerName"`
 	Damage     float64 `json:"damage"`
 	Flags      int     `json:"flags"`
@@ -96,21 +145,131 @@ type PlayerHurtEventData struct {
 }
 
 type NewCollectibleEventData struct {
+	PlayerRef
 	Name    string `json:"name"`
 	ID      int    `json:"id"`
 	Quality int    `json:"quality"`
 }
 
+// PlayerInfoUpdateEventData is a snapshot of the player, hearts are counted
+// in half hearts unless noted otherwise.
 type PlayerInfoUpdateEventData struct {
-	Health       int    `json:"health"`
-	MaxHealth    int    `json:"maxHealth"`
-	Collectibles string `json:"collectibles"`
+	PlayerRef
+	Health       int             `json:"health"`
+	MaxHealth    int             `json:"maxHealth"`
+	Collectibles CollectibleList `json:"collectibles"`
+	// Transformations are the PlayerForm names without prefix, e.g. GUPPY
+	Transformations StringList `json:"transformations"`
+
+	// SoulHearts includes the black hearts
+	SoulHearts  int `json:"soulHearts"`
+	BlackHearts int `json:"blackHearts"`
+	// BoneHearts, GoldenHearts and RottenHearts are counted in hearts
+	BoneHearts    int `json:"boneHearts"`
+	EternalHearts int `json:"eternalHearts"`
+	GoldenHearts  int `json:"goldenHearts"`
+	RottenHearts  int `json:"rottenHearts"`
+
+	Damage float64 `json:"damage"`
+	// Tears is shots per second
+	Tears float64 `json:"tears"`
+	Speed float64 `json:"speed"`
+	Luck  float64 `json:"luck"`
+	Range float64 `json:"range"`
+}
+
+type PlayerDeathEventData struct {
+	PlayerRef
+}
+
+// CollectibleCount is how many of the collectible with ID the player holds.
+// Name is only sent for modded items, their IDs change between runs.
+type CollectibleCount struct {
+	ID   int    `json:"id"`
+	Num  int    `json:"num"`
+	Name string `json:"name,omitempty"`
+}
+
+type CollectibleList []CollectibleCount
+
+func (c *CollectibleList) UnmarshalJSON(data []byte) error {
+	return unmarshalList(data, (*[]CollectibleCount)(c))
+}
+
+type StringList []string
+
+func (s *StringList) UnmarshalJSON(data []byte) error {
+	return unmarshalList(data, (*[]string)(s))
+}
+
+// unmarshalList accepts {} too, the mod cannot tell an empty list from an empty object.
+func unmarshalList[T any](data []byte, list *[]T) error {
+	if string(bytes.TrimSpace(data)) == "{}" {
+		*list = nil
+		return nil
+	}
+	return json.Unmarshal(data, list)
+}
+
+// PlayerTransformationEventData is not sent by the mod, the game emits it when
+// PlayerInfoUpdateEventData.Transformations gains Form.
+type PlayerTransformationEventData struct {
+	PlayerRef
+	Form string `json:"form"`
 }
 
 type GameStartEventData struct {
 	IsContinue bool `json:"isContinue"`
 }
 
+type RoomClearEventData struct {
+	RoomType  int  `json:"roomType"`
+	RoomIndex int  `json:"roomIndex"`
+	IsBoss    bool `json:"isBoss"`
+}
+
+// BossKilledEventData is sent once a boss room is cleared, Type and Variant are
+// the first boss killed in it, 0 when none was killed.
+type BossKilledEventData struct {
+	Type    int `json:"type"`
+	Variant int `json:"variant"`
+}
+
+type NewFloorEventData struct {
+	Stage     int `json:"stage"`
+	StageType int `json:"stageType"`
+}
+
+type ActiveItemUsedEventData struct {
+	PlayerRef
+	ID   int `json:"id"`
+	Slot int `json:"slot"`
+}
+
+type PillUsedEventData struct {
+	PlayerRef
+	Effect int `json:"effect"`
+}
+
+type CardUsedEventData struct {
+	PlayerRef
+	ID int `json:"id"`
+}
+
+type PickupCollectedEventData struct {
+	PlayerRef
+	Kind PickupKind `json:"kind"`
+	// Amount of hearts is in half hearts, they are only counted when the player touches the pickup
+	Amount int `json:"amount"`
+}
+
+type DevilDealTakenEventData struct {
+	PlayerRef
+	ID int `json:"id"`
+	// Price is the heart price, a negative PickupPrice value for special deals
+	Price int `json:"price"`
+}
+
 type UpdateIndicatorData struct {
 	StrengthA int `json:"strengthA"`
 	StrengthB int `json:"strengthB"`
@@ -123,4 +282,26 @@ type ItemDetail struct {
 	Quality      int      `json:"quality"`
 	CraftQuality int      `json:"craftquality"`
 	Tags         []string `json:"tags"`
+	// Pools are the item pools the collectible appears in, e.g. devil or angel
+	Pools []string `json:"pools,omitempty"`
+}
+
+type TrinketDetail struct {
+	ID   int      `json:"id"`
+	Name string   `json:"name"`
+	Tags []string `json:"tags,omitempty"`
+}
+
+// CardDetail covers cards, runes and soul stones, Type is e.g. tarot, suit, rune or soul.
+type CardDetail struct {
+	ID   int    `json:"id"`
+	Name string `json:"name"`
+	Type string `json:"type"`
+}
+
+type PillEffectDetail struct {
+	ID   int    `json:"id"`
+	Name string `json:"name"`
+	// Class is the effect class from pocketitems.xml, e.g. "1+" or "2-"
+	Class string `json:"class,omitempty"`
 }
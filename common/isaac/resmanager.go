@@ -1,60 +1,198 @@
 package isaac
 
 import (
+	configModel "IsaacCoyote/common/config/model"
+	"IsaacCoyote/resources"
 	"encoding/json"
+	"errors"
+	"fmt"
+	"go.uber.org/zap"
+	"io/fs"
 	"os"
+	"path/filepath"
 	"sync"
 )
 
 type ResourceManager struct {
+	config *configModel.Resources
+
+	db      *itemDatabase
+	resLock sync.RWMutex
+}
+
+// itemDatabase is rebuilt on every load and swapped in whole, lookups never
+// see a half loaded database.
+type itemDatabase struct {
 	collectibles      map[int]ItemDetail
 	collectiblesIndex map[string]int
+	// moddedItems are keyed by name, mods get their IDs assigned at runtime
+	moddedItems map[string]ItemDetail
+	trinkets    map[int]TrinketDetail
+	cards       map[int]CardDetail
+	pillEffects map[int]PillEffectDetail
+}
 
-	resLock sync.Mutex
+func newItemDatabase() *itemDatabase {
+	return &itemDatabase{
+		collectibles:      make(map[int]ItemDetail),
+		collectiblesIndex: make(map[string]int),
+		moddedItems:       make(map[string]ItemDetail),
+		trinkets:          make(map[int]TrinketDetail),
+		cards:             make(map[int]CardDetail),
+		pillEffects:       make(map[int]PillEffectDetail),
+	}
 }
 
+// LoadResources loads the embedded database and applies the configured overrides.
 func (m *ResourceManager) LoadResources() error {
-	m.resLock.Lock()
-	defer m.resLock.Unlock()
+	db := newItemDatabase()
+	config := configModel.Resources{}
+	if m.config != nil {
+		config = *m.config
+	}
 
-	err := m.praseItemDetails()
+	err := loadTable(config.Dir, "collectibles.json", db.collectibles)
 	if err != nil {
 		return err
 	}
-	return nil
-}
-
-func (m *ResourceManager) praseItemDetails() error {
-	itemData, err := os.ReadFile("resources/collectibles.json")
+	err = loadTable(config.Dir, "trinkets.json", db.trinkets)
+	if err != nil {
+		return err
+	}
+	err = loadTable(config.Dir, "cards.json", db.cards)
 	if err != nil {
 		return err
 	}
-	ItemDetails := make(map[int]ItemDetail)
-	err = json.Unmarshal(itemData, &ItemDetails)
+	err = loadTable(config.Dir, "pills.json", db.pillEffects)
 	if err != nil {
 		return err
 	}
 
-	for id, item := range ItemDetails {
-		m.collectiblesIndex[item.Name] = id
+	if config.ItemsXML != "" {
+		err = importItemsXML(config.ItemsXML, db)
+		if err != nil {
+			return err
+		}
+	}
+	if config.ItemPoolsXML != "" {
+		err = importItemPoolsXML(config.ItemPoolsXML, db)
+		if err != nil {
+			return err
+		}
+	}
+	if config.PocketItemsXML != "" {
+		err = importPocketItemsXML(config.PocketItemsXML, db)
+		if err != nil {
+			return err
+		}
+	}
+	for _, modDir := range config.ModDirs {
+		err = importModItemsXML(filepath.Join(modDir, "content", "items.xml"), db)
+		if err != nil {
+			return err
+		}
 	}
-	m.collectibles = ItemDetails
 
+	for id, item := range db.collectibles {
+		db.collectiblesIndex[item.Name] = id
+	}
+
+	m.resLock.Lock()
+	m.db = db
+	m.resLock.Unlock()
+
+	zap.L().Info("已加载物品数据",
+		zap.Int("collectibles", len(db.collectibles)),
+		zap.Int("moddedItems", len(db.moddedItems)),
+		zap.Int("trinkets", len(db.trinkets)),
+		zap.Int("cards", len(db.cards)),
+		zap.Int("pillEffects", len(db.pillEffects)))
 	return nil
 }
 
+// loadTable reads the embedded file name and then name in dir, entries in dir
+// replace embedded entries with the same ID. The embedded file must exist, the
+// one in dir is optional.
+func loadTable[T any](dir string, name string, table map[int]T) error {
+	data, err := fs.ReadFile(resources.FS, name)
+	if err != nil {
+		return fmt.Errorf("embedded %s: %w", name, err)
+	}
+	err = json.Unmarshal(data, &table)
+	if err != nil {
+		return fmt.Errorf("embedded %s: %w", name, err)
+	}
+
+	if dir == "" {
+		return nil
+	}
+	path := filepath.Join(dir, name)
+	data, err = os.ReadFile(path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	err = json.Unmarshal(data, &table)
+	if err != nil {
+		return fmt.Errorf("%s: %w", path, err)
+	}
+	return nil
+}
+
+func (m *ResourceManager) getDB() *itemDatabase {
+	m.resLock.RLock()
+	defer m.resLock.RUnlock()
+
+	return m.db
+}
+
+// GetItemByName looks the name up in the game's collectibles first, then in the modded ones.
 func (m *ResourceManager) GetItemByName(itemName string) (ItemDetail, error) {
-	if itemID, ok := m.collectiblesIndex[itemName]; ok {
-		if item, ok := m.collectibles[itemID]; ok {
+	db := m.getDB()
+	if itemID, ok := db.collectiblesIndex[itemName]; ok {
+		if item, ok := db.collectibles[itemID]; ok {
 			return item, nil
 		}
 	}
+	if item, ok := db.moddedItems[itemName]; ok {
+		return item, nil
+	}
 	return ItemDetail{}, NoSuchItemError{Message: "No such item: " + itemName}
 }
 
-func NewResourceManager() *ResourceManager {
+func (m *ResourceManager) GetItemByID(itemID int) (ItemDetail, error) {
+	if item, ok := m.getDB().collectibles[itemID]; ok {
+		return item, nil
+	}
+	return ItemDetail{}, NoSuchItemError{Message: fmt.Sprintf("No such item: %d", itemID)}
+}
+
+func (m *ResourceManager) GetTrinketByID(trinketID int) (TrinketDetail, error) {
+	if trinket, ok := m.getDB().trinkets[trinketID]; ok {
+		return trinket, nil
+	}
+	return TrinketDetail{}, NoSuchItemError{Message: fmt.Sprintf("No such trinket: %d", trinketID)}
+}
+
+func (m *ResourceManager) GetCardByID(cardID int) (CardDetail, error) {
+	if card, ok := m.getDB().cards[cardID]; ok {
+		return card, nil
+	}
+	return CardDetail{}, NoSuchItemError{Message: fmt.Sprintf("No such card: %d", cardID)}
+}
+
+func (m *ResourceManager) GetPillEffectByID(effectID int) (PillEffectDetail, error) {
+	if effect, ok := m.getDB().pillEffects[effectID]; ok {
+		return effect, nil
+	}
+	return PillEffectDetail{}, NoSuchItemError{Message: fmt.Sprintf("No such pill effect: %d", effectID)}
+}
+
+func NewResourceManager(config *configModel.Resources) *ResourceManager {
 	return &ResourceManager{
-		collectibles:      make(map[int]ItemDetail),
-		collectiblesIndex: make(map[string]int),
+		config: config,
+		db:     newItemDatabase(),
 	}
 }
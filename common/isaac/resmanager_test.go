@@ -0,0 +1,39 @@
+package isaac
+
+import (
+	configModel "IsaacCoyote/common/config/model"
+	"testing"
+)
+
+func TestLoadResourcesEmbedded(t *testing.T) {
+	m := NewResourceManager(&configModel.Resources{})
+	if err := m.LoadResources(); err != nil {
+		t.Fatal(err)
+	}
+
+	trinket, err := m.GetTrinketByID(1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if trinket.Name != "Swallowed Penny" {
+		t.Fatalf("trinket 1 = %q, want Swallowed Penny", trinket.Name)
+	}
+	db := m.getDB()
+	for name, size := range map[string]int{
+		"collectibles": len(db.collectibles),
+		"trinkets":     len(db.trinkets),
+		"cards":        len(db.cards),
+		"pills":        len(db.pillEffects),
+	} {
+		if size == 0 {
+			t.Errorf("no %s loaded", name)
+		}
+	}
+}
+
+func TestLoadTableMissingEmbedded(t *testing.T) {
+	err := loadTable(t.TempDir(), "missing.json", make(map[int]TrinketDetail))
+	if err == nil {
+		t.Fatal("loadTable accepted a missing embedded table")
+	}
+}
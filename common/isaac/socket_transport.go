@@ -0,0 +1,182 @@
+package isaac
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"fmt"
+	"go.uber.org/zap"
+	"net"
+	"sync"
+	"time"
+)
+
+const (
+	defaultSocketPort = 8801
+	maxFrameSize      = 1 << 20
+	socketWriteWait   = time.Second
+)
+
+// socketTransport serves a localhost TCP socket the mod connects to when Isaac
+// runs with --luadebug. Every frame is one ModData json followed by a newline.
+type socketTransport struct {
+	port int
+
+	lock     sync.Mutex
+	listener net.Listener
+	conn     net.Conn
+	latest   ModData
+	hasNew   bool
+	err      error
+	changed  chan struct{}
+	accepted chan struct{}
+}
+
+// Open listens on localhost and blocks until the mod connects.
+func (t *socketTransport) Open(ctx context.Context) error {
+	listener, err := net.Listen("tcp", fmt.Sprintf("127.0.0.1:%d", t.port))
+	if err != nil {
+		return err
+	}
+
+	t.lock.Lock()
+	t.listener = listener
+	t.accepted = make(chan struct{})
+	accepted := t.accepted
+	t.lock.Unlock()
+
+	go t.acceptLoop(listener)
+
+	zap.L().Info("等待 mod 连接, 请使用 --luadebug 启动游戏", zap.Int("port", t.port))
+	select {
+	case <-accepted:
+		return nil
+	case <-ctx.Done():
+		_ = t.Close()
+		return ctx.Err()
+	}
+}
+
+// acceptLoop lets a restarted mod take over the connection.
+func (t *socketTransport) acceptLoop(listener net.Listener) {
+	for {
+		conn, err := listener.Accept()
+		if err != nil {
+			return
+		}
+
+		t.lock.Lock()
+		if t.conn != nil {
+			_ = t.conn.Close()
+		}
+		t.conn = conn
+		select {
+		case <-t.accepted:
+		default:
+			close(t.accepted)
+		}
+		t.lock.Unlock()
+
+		zap.L().Debug("mod connected", zap.String("addr", conn.RemoteAddr().String()))
+		go t.readLoop(conn)
+	}
+}
+
+func (t *socketTransport) readLoop(conn net.Conn) {
+	scanner := bufio.NewScanner(conn)
+	scanner.Buffer(make([]byte, 0, 64*1024), maxFrameSize)
+
+	for scanner.Scan() {
+		var data ModData
+		err := json.Unmarshal(scanner.Bytes(), &data)
+
+		t.lock.Lock()
+		if err != nil {
+			t.err = ModDataCorruptedError{
+				Message: fmt.Sprintf("Invalid frame from mod (%d bytes): %s", len(scanner.Bytes()), err),
+			}
+		} else {
+			t.latest = data
+			t.hasNew = true
+		}
+		t.lock.Unlock()
+
+		select {
+		case t.changed <- struct{}{}:
+		default:
+		}
+	}
+
+	t.lock.Lock()
+	if t.conn == conn {
+		t.conn = nil
+	}
+	t.lock.Unlock()
+	_ = conn.Close()
+}
+
+func (t *socketTransport) Changed() <-chan struct{} {
+	return t.changed
+}
+
+func (t *socketTransport) Read() (ModData, bool, error) {
+	t.lock.Lock()
+	defer t.lock.Unlock()
+
+	if t.err != nil {
+		err := t.err
+		t.err = nil
+		return ModData{}, false, err
+	}
+	if !t.hasNew {
+		return ModData{}, false, nil
+	}
+	t.hasNew = false
+	return t.latest, true, nil
+}
+
+func (t *socketTransport) Write(data ModData) error {
+	jsonData, err := json.Marshal(data)
+	if err != nil {
+		return err
+	}
+
+	t.lock.Lock()
+	conn := t.conn
+	t.lock.Unlock()
+	if conn == nil {
+		return NoModDataError{
+			Message: "Mod is not connected",
+		}
+	}
+
+	_ = conn.SetWriteDeadline(time.Now().Add(socketWriteWait))
+	_, err = conn.Write(append(jsonData, '\n'))
+	return err
+}
+
+func (t *socketTransport) Close() error {
+	t.lock.Lock()
+	defer t.lock.Unlock()
+
+	if t.conn != nil {
+		_ = t.conn.Close()
+		t.conn = nil
+	}
+	if t.listener == nil {
+		return nil
+	}
+	err := t.listener.Close()
+	t.listener = nil
+	return err
+}
+
+func newSocketTransport(port int) *socketTransport {
+	if port <= 0 {
+		port = defaultSocketPort
+	}
+	return &socketTransport{
+		port:    port,
+		changed: make(chan struct{}, 1),
+	}
+}
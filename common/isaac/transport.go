@@ -0,0 +1,28 @@
+package isaac
+
+import (
+	configModel "IsaacCoyote/common/config/model"
+	"context"
+)
+
+// Transport carries ModData between the listener and the mod. Both sides always
+// send their whole state, so only the latest ModData from the mod matters.
+type Transport interface {
+	// Open blocks until the mod can be reached or ctx is done.
+	Open(ctx context.Context) error
+	// Changed signals that Read may return new data. It is nil or gets closed
+	// when the transport has to be polled.
+	Changed() <-chan struct{}
+	// Read returns the latest ModData, changed is false when nothing new arrived.
+	// A ModDataCorruptedError keeps the previous data.
+	Read() (data ModData, changed bool, err error)
+	Write(data ModData) error
+	Close() error
+}
+
+func newTransport(config *configModel.Isaac) Transport {
+	if config.Transport == configModel.SOCKET {
+		return newSocketTransport(config.SocketPort)
+	}
+	return newFileTransport(config)
+}
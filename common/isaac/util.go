@@ -1,35 +1,87 @@
 package isaac
 
 import (
+	configModel "IsaacCoyote/common/config/model"
 	"IsaacCoyote/util"
+	"context"
 	"fmt"
 	"go.uber.org/zap"
 	"os"
-	"path"
 	"path/filepath"
 	"strings"
 	"time"
 )
 
-func waitForProcess(processName string) uint32 {
+const defaultProcessName = "isaac-ng.exe"
+
+func waitForProcess(ctx context.Context, processName string) (uint32, error) {
 	for {
 		pid, err := util.GetProcPID(processName)
 		if err != nil {
 			zap.L().Error(fmt.Sprintf("未找到 [%s] 进程", processName))
-			time.Sleep(5 * time.Second)
+			select {
+			case <-ctx.Done():
+				return 0, ctx.Err()
+			case <-time.After(5 * time.Second):
+			}
 			continue
 		}
-		return pid
+		return pid, nil
 	}
 }
 
-func getModDataFile(isaacPID uint32) (string, error) {
+// locateModData resolves the data file shared with the mod. The game process is
+// only looked up when the config sets neither save_file, data_dir nor game_dir.
+func locateModData(ctx context.Context, config *configModel.Isaac) (string, error) {
+	if config.SaveFile != "" {
+		return config.SaveFile, nil
+	}
+
+	dataDir := config.DataDir
+	if dataDir == "" {
+		gameDir := config.GameDir
+		if gameDir == "" {
+			processName := config.ProcessName
+			if processName == "" {
+				processName = defaultProcessName
+			}
+			pid, err := waitForProcess(ctx, processName)
+			if err != nil {
+				return "", err
+			}
+			gameDir, err = getGameDir(pid)
+			if err != nil {
+				return "", err
+			}
+		}
+		dataDir = filepath.Join(gameDir, "data", "isaac-coyote")
+	}
+	return getModDataFile(dataDir, config.SaveSlot)
+}
+
+// getGameDir returns the directory of the game executable.
+// Under Wine or Proton the executable is resolved to its path on the host.
+func getGameDir(isaacPID uint32) (string, error) {
 	procFilePath, err := util.GetProcPath(isaacPID)
 	if err != nil {
 		return "", err
 	}
-	isaacDir := filepath.Dir(procFilePath)
-	modDataPath := filepath.Join(isaacDir, "data", "isaac-coyote")
+	return filepath.Dir(procFilePath), nil
+}
+
+// getModDataFile returns saveN.dat of saveSlot in modDataPath, or the latest
+// written save*.dat when saveSlot is 0.
+func getModDataFile(modDataPath string, saveSlot int) (string, error) {
+	if saveSlot > 0 {
+		filePath := filepath.Join(modDataPath, fmt.Sprintf("save%d.dat", saveSlot))
+		_, err := os.Stat(filePath)
+		if err != nil {
+			return "", NoModDataError{
+				Message: fmt.Sprintf("No mod data file for save slot %d: %s", saveSlot, err),
+			}
+		}
+		return filePath, nil
+	}
 
 	var latestModTime int64
 	var latestDataFile string
@@ -39,7 +91,7 @@ func getModDataFile(isaacPID uint32) (string, error) {
 	}
 	for _, entry := range entries {
 		if !entry.IsDir() && strings.HasPrefix(entry.Name(), "save") && strings.HasSuffix(entry.Name(), ".dat") {
-			filePath := path.Join(modDataPath, entry.Name())
+			filePath := filepath.Join(modDataPath, entry.Name())
 			fileInfo, err := os.Stat(filePath)
 			if err != nil {
 				return "", err
@@ -51,5 +103,46 @@ func getModDataFile(isaacPID uint32) (string, error) {
 			}
 		}
 	}
+	if latestDataFile == "" {
+		return "", NoModDataError{
+			Message: "No mod data file in " + modDataPath,
+		}
+	}
 	return latestDataFile, nil
 }
+
+// writeFileAtomic writes to a temp file next to name and renames it over name,
+// so the mod never loads a half-written file. Renaming on Windows fails while
+// Isaac has the file open, which is retried.
+func writeFileAtomic(name string, data []byte, perm os.FileMode) error {
+	tmpFile, err := os.CreateTemp(filepath.Dir(name), "."+filepath.Base(name)+".*.tmp")
+	if err != nil {
+		return err
+	}
+	tmpName := tmpFile.Name()
+	defer os.Remove(tmpName)
+
+	_, err = tmpFile.Write(data)
+	if err == nil {
+		err = tmpFile.Sync()
+	}
+	closeErr := tmpFile.Close()
+	if err != nil {
+		return err
+	}
+	if closeErr != nil {
+		return closeErr
+	}
+	err = os.Chmod(tmpName, perm)
+	if err != nil {
+		return err
+	}
+
+	for attempt := 1; ; attempt++ {
+		err = os.Rename(tmpName, name)
+		if err == nil || attempt == 5 {
+			return err
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
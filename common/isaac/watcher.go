@@ -0,0 +1,69 @@
+package isaac
+
+import (
+	"context"
+	"github.com/fsnotify/fsnotify"
+	"go.uber.org/zap"
+	"path/filepath"
+)
+
+// dataWatcher signals writes to the mod data file. The parent directory is
+// watched so the file is still seen after being replaced.
+type dataWatcher struct {
+	path    string
+	watcher *fsnotify.Watcher
+	changed chan struct{}
+}
+
+// run forwards change notifications until ctx is cancelled or the watcher fails.
+// changed is closed on return, so a receiver can fall back to polling.
+func (w *dataWatcher) run(ctx context.Context) {
+	defer close(w.changed)
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case event, ok := <-w.watcher.Events:
+			if !ok {
+				return
+			}
+			if filepath.Clean(event.Name) != w.path || !event.Has(fsnotify.Write|fsnotify.Create|fsnotify.Rename) {
+				continue
+			}
+			// coalesce bursts, one pending notification is enough to re-read the file
+			select {
+			case w.changed <- struct{}{}:
+			default:
+			}
+		case err, ok := <-w.watcher.Errors:
+			if !ok {
+				return
+			}
+			zap.L().Error("监听数据文件失败", zap.Error(err))
+		}
+	}
+}
+
+func (w *dataWatcher) Close() error {
+	return w.watcher.Close()
+}
+
+func newDataWatcher(path string) (*dataWatcher, error) {
+	watcher, err := fsnotify.NewWatcher()
+	if err != nil {
+		return nil, err
+	}
+	path = filepath.Clean(path)
+	err = watcher.Add(filepath.Dir(path))
+	if err != nil {
+		_ = watcher.Close()
+		return nil, err
+	}
+
+	return &dataWatcher{
+		path:    path,
+		watcher: watcher,
+		changed: make(chan struct{}, 1),
+	}, nil
+}
@@ -2,11 +2,28 @@ local mod                = RegisterMod("IsaacCoyote", 1)
 
 -- Includes
 local json               = require("json")
+--- only available when the game is started with --luadebug
+local hasSocket, socket  = pcall(require, "socket")
 
 ---Constants
-local VERSION            = "1.0.0"
+local VERSION            = "1.1.0"
 local HEARTBEAT_INTERVAL = 5 * 60 -- 5 seconds
 local UPDATE_FREQUENCY   = 15     -- 15 frames: 1/4 seconds
+local SOCKET_FREQUENCY   = 2      -- 2 frames: 1/30 seconds
+local SOCKET_HOST        = "127.0.0.1"
+local SOCKET_PORT        = 8801 -- default of modSettings.SocketPort
+local SOCKET_RETRY       = 5 * 60 -- 5 seconds
+--- heart pickups PickupCollectedEvent counts, amount in half hearts
+local HEART_PICKUPS      = {
+    [HeartSubType.HEART_FULL] = { kind = "redHeart", amount = 2 },
+    [HeartSubType.HEART_HALF] = { kind = "redHeart", amount = 1 },
+    [HeartSubType.HEART_DOUBLEPACK] = { kind = "redHeart", amount = 4 },
+    [HeartSubType.HEART_SCARED] = { kind = "redHeart", amount = 2 },
+    [HeartSubType.HEART_BLENDED] = { kind = "redHeart", amount = 2 },
+    [HeartSubType.HEART_SOUL] = { kind = "soulHeart", amount = 2 },
+    [HeartSubType.HEART_HALF_SOUL] = { kind = "soulHeart", amount = 1 },
+    [HeartSubType.HEART_BLACK] = { kind = "soulHeart", amount = 2 },
+}
 local COYOTE_CALLBACKS   = {
     C_ON_INDICATOR_UPDATE = "update_indicator",
     C_ON_CONNECT = "connect",
@@ -18,6 +35,8 @@ local modSettings        = {
     IndicatorOffsetX = 0,
     IndicatorOffsetY = 0,
     IndicatorSize = 10,
+    --- must match socket_port of IsaacCoyote, kept across restarts
+    SocketPort = SOCKET_PORT,
     time = 0,
 }
 
@@ -26,7 +45,15 @@ local indicatorData      = {
     strengthB = 0,
 }
 
-local localPlayerRNG
+--- last seen state of every player entity, for events derived from changes
+local playerStates       = {}
+--- coins, bombs and keys are shared by all players
+local sharedState        = nil
+local pendingDevilDeals  = {}
+--- heart pickups of the current room already reported, keyed by GetPtrHash
+local collectedHearts    = {}
+--- the first boss killed in the current room, reported once the room is cleared
+local roomBoss           = nil
 
 local isPrevGameExited   = false
 local isPrevGameLiving   = true
@@ -35,11 +62,17 @@ local isConnected        = false
 local isRecviedHeartbeat = false
 local heartbeatTimer     = HEARTBEAT_INTERVAL
 local dataTable
+local socketTransport
 local font               = Font()
 local game               = Game()
 font:Load("font/cjk/lanapixel.fnt")
 
 --- func
+--- a modConfig written by an older version has no port
+local function getSocketPort()
+    return tonumber(modSettings.SocketPort) or SOCKET_PORT
+end
+
 local function initConfigMenu()
     if ModConfigMenu == nil then
         return
@@ -105,6 +138,130 @@ local function initConfigMenu()
             Info = { "Indicator Size" }
         }
     )
+
+    ModConfigMenu.AddSetting(
+        "IsaacCoyote",
+        "Connection",
+        {
+            Type = ModConfigMenu.OptionType.NUMBER,
+            CurrentSetting = function()
+                return getSocketPort()
+            end,
+            Minimum = 1024,
+            Maximum = 65535,
+            Display = function()
+                return "Socket Port: " .. getSocketPort()
+            end,
+            OnChange = function(n)
+                modSettings.SocketPort = n
+                modSettings.time = Isaac.GetFrameCount()
+                dataTable.WriteTable()
+                if socketTransport and socketTransport.IsConnected() then
+                    --- the port is read from the save file on the next start
+                    mod:SaveData(json.encode(dataTable.data))
+                end
+            end,
+            Info = { "Port of the SOCKET transport, must match socket_port of IsaacCoyote", "Requires --luadebug" }
+        }
+    )
+end
+
+--- a localhost socket served by IsaacCoyote, each line is one data table
+local function newSocketTransport()
+    local object = {
+        conn = nil,
+        inBuffer = "",
+        outBuffer = "",
+        latest = nil,
+        retryTimer = 0,
+        port = nil,
+    }
+
+    function object.IsConnected()
+        return object.conn ~= nil
+    end
+
+    function object._close()
+        if object.conn then
+            object.conn:close()
+        end
+        object.conn = nil
+        object.inBuffer = ""
+        object.outBuffer = ""
+        object.latest = nil
+    end
+
+    function object._connect()
+        object.retryTimer = object.retryTimer - 1
+        if object.retryTimer > 0 then
+            return
+        end
+        object.retryTimer = SOCKET_RETRY
+
+        local port = getSocketPort()
+        local conn = socket.tcp()
+        conn:settimeout(0.01)
+        if conn:connect(SOCKET_HOST, port) then
+            conn:settimeout(0)
+            object.conn = conn
+            object.port = port
+        else
+            conn:close()
+        end
+    end
+
+    --- called every frame, reads whole lines and sends what is left to send
+    function object.Poll()
+        --- reconnect when the port was changed in the config menu
+        if object.conn and object.port ~= getSocketPort() then
+            object._close()
+            object.retryTimer = 0
+        end
+        if not object.conn then
+            object._connect()
+            return
+        end
+
+        while true do
+            local line, err, partial = object.conn:receive("*l")
+            if line then
+                object.latest = object.inBuffer .. line
+                object.inBuffer = ""
+            elseif err == "timeout" then
+                object.inBuffer = object.inBuffer .. (partial or "")
+                break
+            else
+                object._close()
+                return
+            end
+        end
+
+        if object.outBuffer ~= "" then
+            local sent, err, lastSent = object.conn:send(object.outBuffer)
+            if sent then
+                object.outBuffer = ""
+            elseif err == "timeout" then
+                object.outBuffer = object.outBuffer:sub(lastSent + 1)
+            else
+                object._close()
+            end
+        end
+    end
+
+    function object.Load()
+        local raw = object.latest
+        object.latest = nil
+        return raw
+    end
+
+    --- the whole state is sent every time, a frame still being sent is enough
+    function object.Save(raw)
+        if object.outBuffer == "" then
+            object.outBuffer = raw .. "\n"
+        end
+    end
+
+    return object
 end
 
 local function newDataTable()
@@ -115,9 +272,14 @@ local function newDataTable()
             receive = {},
             modConfig = modSettings,
         },
-        newMessages = {
+        --- messages are resent until the host acks them
+        pendingMessages = {
 
         },
+        nextMsgID = 1,
+        modSession = Random(),
+        hostSession = nil,
+        lastHostMsgID = 0,
         callbacks = {
             [COYOTE_CALLBACKS.C_ON_HEARTBEAT] = {
 
@@ -133,13 +295,40 @@ local function newDataTable()
 
     function object.PushMessage(eventObj)
         eventObj.frameCount = Isaac.GetFrameCount()
-        object.newMessages[#object.newMessages + 1] = eventObj
+        eventObj.id = object.nextMsgID
+        object.nextMsgID = object.nextMsgID + 1
+        object.pendingMessages[#object.pendingMessages + 1] = eventObj
+    end
+
+    function object.ClearPending()
+        object.pendingMessages = {}
     end
 
     function object.WriteTable()
-        object.data.send = object.newMessages
+        object.data.send = object.pendingMessages
         object.data.modConfig = modSettings
-        mod:SaveData(json.encode(object.data))
+        object.data.modSession = object.modSession
+        object.data.modAck = object.lastHostMsgID
+        object.data.hostSession = object.hostSession
+        if socketTransport and socketTransport.IsConnected() then
+            socketTransport.Save(json.encode(object.data))
+        else
+            mod:SaveData(json.encode(object.data))
+        end
+    end
+
+    --- drop the messages the host has processed
+    function object._prune()
+        if object.data.modSession ~= object.modSession or not object.data.hostAck then
+            return
+        end
+        local pending = {}
+        for _, message in ipairs(object.pendingMessages) do
+            if message.id > object.data.hostAck then
+                pending[#pending + 1] = message
+            end
+        end
+        object.pendingMessages = pending
     end
 
     function object.updateData()
@@ -155,11 +344,22 @@ local function newDataTable()
             modSettings = object.data.modConfig
         end
 
+        if object.data.hostSession ~= object.hostSession then
+            object.hostSession = object.data.hostSession
+            object.lastHostMsgID = 0
+        end
+        object._prune()
+
         if #object.data.receive > 0 then
             for _, event in ipairs(object.data.receive) do
-                if object.callbacks[event.type] then
-                    for _, callback in ipairs(object.callbacks[event.type]) do
-                        callback(event.message)
+                --- the host resends until acked, handle every message once
+                local id = event.id or 0
+                if id > object.lastHostMsgID then
+                    object.lastHostMsgID = id
+                    if object.callbacks[event.type] then
+                        for _, callback in ipairs(object.callbacks[event.type]) do
+                            callback(event.message)
+                        end
                     end
                 end
             end
@@ -175,7 +375,15 @@ local function newDataTable()
     end
 
     function object._loadData()
-        object.rawData = Isaac.LoadModData(mod)
+        if socketTransport and socketTransport.IsConnected() then
+            local raw = socketTransport.Load()
+            if not raw then
+                error("no new data")
+            end
+            object.rawData = raw
+        else
+            object.rawData = Isaac.LoadModData(mod)
+        end
         object.data = json.decode(object.rawData)
     end
 
@@ -199,23 +407,37 @@ local function newHeartbeatMsg()
     }
 end
 
-local function getPlayerRNG(player)
-    if player and player:ToPlayer() then
-        return player:GetCollectibleRNG(CollectibleType.COLLECTIBLE_SAD_ONION):GetSeed()
+--- calls fn for every player like the game HUD numbers them, twins such as
+--- Jacob & Esau or Tainted Forgotten and his soul count as their main twin
+local function forEachPlayer(fn)
+    local index = 0
+    for i = 0, game:GetNumPlayers() - 1 do
+        local player = game:GetPlayer(i)
+        if GetPtrHash(player:GetMainTwin()) == GetPtrHash(player) then
+            fn(player, {
+                playerIndex = index,
+                controllerIndex = player.ControllerIndex,
+                playerType = player:GetPlayerType(),
+            })
+            index = index + 1
+        end
     end
 end
 
-local function getLocalPlayer()
-    if localPlayerRNG then
-        local playerNums = game.GetNumPlayers(game)
-        for i = 0, playerNums - 1 do
-            local player = game:GetPlayer(i)
-            if player and getPlayerRNG(player) == localPlayerRNG then
-                return player
-            end
-        end
+--- the PlayerRef of the events of player, nil if it is not a player
+local function getPlayerRef(player)
+    player = player and player:ToPlayer()
+    if not player then
+        return nil
     end
-    return game:GetPlayer(0) --- HACK: Fallback ?
+    local mainTwin = GetPtrHash(player:GetMainTwin())
+    local ref
+    forEachPlayer(function(p, r)
+        if GetPtrHash(p) == mainTwin then
+            ref = r
+        end
+    end)
+    return ref
 end
 
 local function checkConnection()
@@ -236,15 +458,77 @@ local function checkConnection()
     end
 end
 
-local function newPlayerInfoMsg()
-    player = getLocalPlayer()
-    if player then
+--- GetBlackHearts is a bit mask with one bit per full soul heart
+local function countBlackHearts(player)
+    local mask = player:GetBlackHearts()
+    local count = 0
+    while mask > 0 do
+        count = count + (mask & 1)
+        mask = mask >> 1
+    end
+    return math.min(count * 2, player:GetSoulHearts())
+end
+
+--- the collectibles of player by ID, including modded ones
+local function getCollectibles(player)
+    local collectibles = {}
+    local itemConfig = Isaac.GetItemConfig()
+    local maxID = itemConfig:GetCollectibles().Size - 1
+    for id = 1, maxID do
+        local num = player:GetCollectibleNum(id, true)
+        if num > 0 then
+            local collectible = { id = id, num = num }
+            -- modded items get their IDs at runtime, the host matches them by name
+            if id >= CollectibleType.NUM_COLLECTIBLES then
+                local config = itemConfig:GetCollectible(id)
+                if config then
+                    collectible.name = config.Name
+                end
+            end
+            table.insert(collectibles, collectible)
+        end
+    end
+    return collectibles
+end
+
+--- names of the transformations of the player, e.g. GUPPY for PLAYERFORM_GUPPY
+local function getTransformations(player)
+    local transformations = {}
+    for name, form in pairs(PlayerForm) do
+        if name ~= "NUM_PLAYER_FORMS" and player:HasPlayerForm(form) then
+            table.insert(transformations, (name:gsub("^PLAYERFORM_", "")))
+        end
+    end
+    return transformations
+end
+
+local function newPlayerInfoMsgs()
+    forEachPlayer(function(player, ref)
         dataTable.PushMessage(newEventMsg("PlayerInfoUpdateEvent", {
+            playerIndex = ref.playerIndex,
+            controllerIndex = ref.controllerIndex,
+            playerType = ref.playerType,
+
             health = player:GetHearts(),
             maxHealth = player:GetMaxHearts(),
-            collectibles = Isaac.ExecuteCommand("listcollectibles")
+            collectibles = getCollectibles(player),
+            transformations = getTransformations(player),
+
+            soulHearts = player:GetSoulHearts(),
+            blackHearts = countBlackHearts(player),
+            boneHearts = player:GetBoneHearts(),
+            eternalHearts = player:GetEternalHearts(),
+            goldenHearts = player:GetGoldenHearts(),
+            rottenHearts = player:GetRottenHearts(),
+
+            damage = player.Damage,
+            tears = 30 / (player.MaxFireDelay + 1),
+            speed = player.MoveSpeed,
+            luck = player.Luck,
+            -- TearRange is in world units, the stat shown in game is a 40th of it
+            range = player.TearRange / 40,
         }))
-    end
+    end)
 end
 
 local function RenderIndicator()
@@ -289,6 +573,8 @@ end
 local function onConnect(data)
     heartbeatTimer = HEARTBEAT_INTERVAL
     isConnected = true
+    --- a new host, whatever was pushed before is stale
+    dataTable.ClearPending()
 end
 
 local function onHeartbeat(data)
@@ -305,25 +591,205 @@ end
 --- Mod Callbacks
 function mod:onRender()
     local frameCount = Isaac.GetFrameCount()
-    if frameCount % UPDATE_FREQUENCY == 0 then
+    local syncFrequency = UPDATE_FREQUENCY
+    if socketTransport then
+        socketTransport.Poll()
+        if socketTransport.IsConnected() then
+            syncFrequency = SOCKET_FREQUENCY
+        end
+    end
+
+    if frameCount % syncFrequency == 0 then
         dataTable.updateData()
     end
     checkConnection()
 
     if isConnected then
         if frameCount % UPDATE_FREQUENCY == 0 then
-            newPlayerInfoMsg()
+            newPlayerInfoMsgs()
+        end
+        if frameCount % syncFrequency == 0 then
             dataTable.WriteTable()
-            dataTable.newMessages = {}
         end
     end
     RenderIndicator()
 end
 
-function mod:regLocalPlayerRNG(player)
-    if player and player.FrameCount == 1 then
-        localPlayerRNG = getPlayerRNG(player)
+local function pushEvent(eventType, eventData)
+    if not isConnected then
+        return
+    end
+    dataTable.PushMessage(newEventMsg(eventType, eventData))
+end
+
+--- pushes an event that belongs to player together with its PlayerRef
+local function pushPlayerEvent(eventType, player, eventData)
+    local ref = getPlayerRef(player)
+    if not ref then
+        return
+    end
+    for key, value in pairs(ref) do
+        eventData[key] = value
+    end
+    pushEvent(eventType, eventData)
+end
+
+local function newSharedState(player)
+    return {
+        coins = player:GetNumCoins(),
+        bombs = player:GetNumBombs(),
+        keys = player:GetNumKeys(),
+    }
+end
+
+local function newPlayerState(player)
+    local queuedItem = player.QueuedItem.Item
+    return {
+        queuedItemID = queuedItem and queuedItem:IsCollectible() and queuedItem.ID or nil,
+    }
+end
+
+--- emits PickupCollectedEvent for coins, bombs and keys and NewCollectibleEvent from changes
+--- of the player state, shared pickups are credited to the first player updated after the change
+function mod:onPlayerUpdate(player)
+    local pickups = {}
+
+    local shared = newSharedState(player)
+    if sharedState then
+        table.insert(pickups, { kind = "coin", amount = shared.coins - sharedState.coins })
+        table.insert(pickups, { kind = "bomb", amount = shared.bombs - sharedState.bombs })
+        table.insert(pickups, { kind = "key", amount = shared.keys - sharedState.keys })
+    end
+    sharedState = shared
+
+    local key = GetPtrHash(player)
+    local state = newPlayerState(player)
+    local playerState = playerStates[key]
+    if playerState then
+        --- an item is held above the head once it is picked up
+        if state.queuedItemID and state.queuedItemID ~= playerState.queuedItemID then
+            local item = player.QueuedItem.Item
+            pushPlayerEvent("NewCollectibleEvent", player, {
+                name = item.Name,
+                id = item.ID,
+                quality = item.Quality,
+            })
+            local pendingDevilDeal = pendingDevilDeals[key]
+            if pendingDevilDeal and pendingDevilDeal.id == item.ID then
+                pushPlayerEvent("DevilDealTakenEvent", player, pendingDevilDeal)
+            end
+            pendingDevilDeals[key] = nil
+        end
+    end
+    playerStates[key] = state
+
+    for _, pickup in ipairs(pickups) do
+        if pickup.amount > 0 then
+            pushPlayerEvent("PickupCollectedEvent", player, pickup)
+        end
+    end
+end
+
+--- whether the player takes the heart on touch, full health or an empty wallet leave it on the floor
+local function canPickHeart(player, pickup, heart)
+    if pickup.Price > 0 and player:GetNumCoins() < pickup.Price then
+        return false
+    end
+    if pickup.SubType == HeartSubType.HEART_BLACK then
+        return player:CanPickBlackHearts()
+    elseif pickup.SubType == HeartSubType.HEART_BLENDED then
+        return player:CanPickRedHearts() or player:CanPickSoulHearts()
+    elseif heart.kind == "redHeart" then
+        return player:CanPickRedHearts()
+    end
+    return player:CanPickSoulHearts()
+end
+
+--- healing from items or the room does not count, only touching a heart does
+local function onHeartCollision(pickup, player)
+    local heart = HEART_PICKUPS[pickup.SubType]
+    local key = GetPtrHash(pickup)
+    if not heart or collectedHearts[key] or not canPickHeart(player, pickup, heart) then
+        return
+    end
+    collectedHearts[key] = true
+    pushPlayerEvent("PickupCollectedEvent", player, { kind = heart.kind, amount = heart.amount })
+end
+
+function mod:onPickupCollision(pickup, collider)
+    local player = collider:ToPlayer()
+    if not player then
+        return
+    end
+    if pickup.Variant == PickupVariant.PICKUP_HEART then
+        onHeartCollision(pickup, player)
+        return
+    end
+    if pickup.Variant ~= PickupVariant.PICKUP_COLLECTIBLE then
+        return
+    end
+    --- negative prices are paid with hearts
+    if pickup.Price < 0 and pickup.Price ~= PickupPrice.PRICE_FREE then
+        pendingDevilDeals[GetPtrHash(player)] = { id = pickup.SubType, price = pickup.Price }
+    end
+end
+
+function mod:onRoomClear()
+    local room = game:GetRoom()
+    local isBoss = room:GetType() == RoomType.ROOM_BOSS
+    pushEvent("RoomClearEvent", {
+        roomType = room:GetType(),
+        roomIndex = game:GetLevel():GetCurrentRoomIndex(),
+        isBoss = isBoss,
+    })
+    --- multi-part bosses and boss pairs die many times, the clear happens once
+    if isBoss then
+        local boss = roomBoss or { type = 0, variant = 0 }
+        pushEvent("BossKilledEvent", {
+            type = boss.type,
+            variant = boss.variant,
+        })
     end
+    roomBoss = nil
+end
+
+function mod:onBossKilled(entity)
+    if roomBoss or not entity:IsBoss() then
+        return
+    end
+    roomBoss = {
+        type = entity.Type,
+        variant = entity.Variant,
+    }
+end
+
+function mod:onNewRoom()
+    roomBoss = nil
+    collectedHearts = {}
+end
+
+function mod:onNewLevel()
+    local level = game:GetLevel()
+    pushEvent("NewFloorEvent", {
+        stage = level:GetStage(),
+        stageType = level:GetStageType(),
+    })
+end
+
+function mod:onUseItem(collectibleType, rng, player, useFlags, activeSlot)
+    --- items triggered by other effects are not used from a slot
+    if not activeSlot or activeSlot < 0 then
+        return
+    end
+    pushPlayerEvent("ActiveItemUsedEvent", player, { id = collectibleType, slot = activeSlot })
+end
+
+function mod:onUsePill(pillEffect, player, useFlags)
+    pushPlayerEvent("PillUsedEvent", player, { effect = pillEffect })
+end
+
+function mod:onUseCard(card, player, useFlags)
+    pushPlayerEvent("CardUsedEvent", player, { id = card })
 end
 
 function mod:onPlayerDamage(entity, damage, flags, source, countdown)
@@ -332,14 +798,13 @@ function mod:onPlayerDamage(entity, damage, flags, source, countdown)
     end
 
     local player = entity:ToPlayer()
-    if player and getPlayerRNG(player) == localPlayerRNG then
-        local eventData = {
+    if player then
+        pushPlayerEvent("PlayerHurtEvent", player, {
             playerName = player:GetName(),
             damage = damage,
             flags = flags,
             source = source.Type,
-        }
-        dataTable.PushMessage(newEventMsg("PlayerHurtEvent", eventData))
+        })
     end
 end
 
@@ -350,8 +815,8 @@ function mod:onPlayerDeath(entity)
 
 
     local player = entity:ToPlayer()
-    if player and getPlayerRNG(player) == localPlayerRNG then
-        dataTable.PushMessage(newEventMsg("PlayerDeathEvent", {}))
+    if player then
+        pushPlayerEvent("PlayerDeathEvent", player, {})
     end
 end
 
@@ -373,6 +838,9 @@ function mod:onGameStarted(isContinue)
     end
 
     dataTable.PushMessage(newEventMsg("GameStartEvent", { isContinue = isContinue }))
+    playerStates = {}
+    sharedState = nil
+    pendingDevilDeals = {}
     isPrevGameExited = false
     isPrevGameLiving = true
 end
@@ -380,12 +848,19 @@ end
 ---Main
 ---clear the save data on mod initialization
 dataTable = newDataTable()
+if hasSocket then
+    socketTransport = newSocketTransport()
+end
 if not pcall(dataTable._loadData) then
-    mod:SaveData('{"send":[],"receive":[], "modConfig":{"IndicatorOffsetX":0,"IndicatorOffsetY":0,"IndicatorSize":10,"time":-1}') -- Using time=-1 resets config to defaults on next load
+    mod:SaveData('{"send":[],"receive":[], "modConfig":{"IndicatorOffsetX":0,"IndicatorOffsetY":0,"IndicatorSize":10,"SocketPort":8801,"time":-1}') -- Using time=-1 resets config to defaults on next load
 else
     if not dataTable.data.modConfig then
         dataTable.data.modConfig = modSettings
     end
+    --- unlike the indicator settings the port survives the reset, the socket has to find IsaacCoyote before any config arrives
+    if tonumber(dataTable.data.modConfig.SocketPort) then
+        modSettings.SocketPort = tonumber(dataTable.data.modConfig.SocketPort)
+    end
     dataTable.data.modConfig.time = -1
 end
 
@@ -397,7 +872,6 @@ dataTable.RegisterCallback(COYOTE_CALLBACKS.C_ON_INDICATOR_UPDATE, onUpdateIndic
 
 mod:AddCallback(ModCallbacks.MC_POST_RENDER, mod.onRender)
 
-mod:AddCallback(ModCallbacks.MC_POST_PEFFECT_UPDATE, mod.regLocalPlayerRNG)
 
 mod:AddCallback(ModCallbacks.MC_ENTITY_TAKE_DMG, mod.onPlayerDamage)
 mod:AddCallback(ModCallbacks.MC_POST_ENTITY_KILL, mod.onPlayerDeath)
@@ -405,3 +879,13 @@ mod:AddCallback(ModCallbacks.MC_POST_ENTITY_KILL, mod.onPlayerDeath)
 mod:AddCallback(ModCallbacks.MC_PRE_GAME_EXIT, mod.onExit)
 mod:AddCallback(ModCallbacks.MC_POST_GAME_END, mod.onGameEnd)
 mod:AddCallback(ModCallbacks.MC_POST_GAME_STARTED, mod.onGameStarted)
+
+mod:AddCallback(ModCallbacks.MC_POST_PEFFECT_UPDATE, mod.onPlayerUpdate)
+mod:AddCallback(ModCallbacks.MC_PRE_PICKUP_COLLISION, mod.onPickupCollision)
+mod:AddCallback(ModCallbacks.MC_PRE_SPAWN_CLEAN_AWARD, mod.onRoomClear)
+mod:AddCallback(ModCallbacks.MC_POST_ENTITY_KILL, mod.onBossKilled)
+mod:AddCallback(ModCallbacks.MC_POST_NEW_ROOM, mod.onNewRoom)
+mod:AddCallback(ModCallbacks.MC_POST_NEW_LEVEL, mod.onNewLevel)
+mod:AddCallback(ModCallbacks.MC_USE_ITEM, mod.onUseItem)
+mod:AddCallback(ModCallbacks.MC_USE_PILL, mod.onUsePill)
+mod:AddCallback(ModCallbacks.MC_USE_CARD, mod.onUseCard)
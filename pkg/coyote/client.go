@@ -0,0 +1,340 @@
+package coyote
+
+import (
+	"IsaacCoyote/pkg/coyote/enums"
+	"context"
+	"encoding/json"
+	"fmt"
+	"github.com/gorilla/websocket"
+	"go.uber.org/zap"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Client connects to a remote DG-LAB socket relay and acts as the terminal.
+// It reconnects with exponential backoff until Run's context is cancelled.
+type Client struct {
+	config *Config
+
+	lock         sync.RWMutex
+	conn         *websocket.Conn
+	clientID     string
+	targetID     string
+	strengthData StrengthData
+	isBound      bool
+
+	writeLock sync.Mutex
+
+	callbackLock sync.RWMutex
+	callbacks    map[enums.SessionEvent][]SessionCallback
+}
+
+// Run keeps the client connected until ctx is cancelled.
+func (c *Client) Run(ctx context.Context) error {
+	delay := c.config.reconnectMinDelay()
+	for {
+		established, err := c.connect(ctx)
+		if ctx.Err() != nil {
+			return nil
+		}
+		if established {
+			delay = c.config.reconnectMinDelay()
+		}
+		zap.L().Warn("Relay connection lost, reconnecting", zap.Error(err), zap.Duration("delay", delay))
+
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-time.After(delay):
+		}
+		delay = min(delay*2, c.config.reconnectMaxDelay())
+	}
+}
+
+// connect dials the relay and reads until the connection drops.
+// established reports whether the relay assigned us a clientID.
+func (c *Client) connect(ctx context.Context) (established bool, err error) {
+	conn, _, err := websocket.DefaultDialer.DialContext(ctx, c.config.RelayURL, nil)
+	if err != nil {
+		return false, err
+	}
+	defer c.dropConnection(conn)
+
+	c.lock.Lock()
+	c.conn = conn
+	c.lock.Unlock()
+
+	readTimeout := max(c.config.evictTimeout(), 2*c.config.heartbeatInterval())
+	_ = conn.SetReadDeadline(time.Now().Add(readTimeout))
+	conn.SetPongHandler(func(string) error {
+		return conn.SetReadDeadline(time.Now().Add(readTimeout))
+	})
+
+	pingCtx, cancel := context.WithCancel(ctx)
+	var wg sync.WaitGroup
+	defer func() {
+		cancel()
+		_ = conn.Close()
+		wg.Wait()
+	}()
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		c.ping(pingCtx, conn)
+	}()
+
+	for {
+		_, rawMsg, err := conn.ReadMessage()
+		if err != nil {
+			return established, err
+		}
+		_ = conn.SetReadDeadline(time.Now().Add(readTimeout))
+
+		message := WSMessage{}
+		err = json.Unmarshal(rawMsg, &message)
+		if err != nil {
+			zap.L().Error("Invalid message from relay", zap.ByteString("message", rawMsg), zap.Error(err))
+			continue
+		}
+		if c.handleMessage(message) {
+			established = true
+		}
+	}
+}
+
+// ping keeps the read deadline alive and closes conn once ctx is done.
+func (c *Client) ping(ctx context.Context, conn *websocket.Conn) {
+	ticker := time.NewTicker(c.config.heartbeatInterval())
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			_ = conn.Close()
+			return
+		case <-ticker.C:
+		}
+
+		c.writeLock.Lock()
+		err := conn.WriteControl(websocket.PingMessage, nil, time.Now().Add(10*time.Second))
+		c.writeLock.Unlock()
+		if err != nil {
+			zap.L().Error("Failed to ping relay", zap.Error(err))
+		}
+	}
+}
+
+func (c *Client) dropConnection(conn *websocket.Conn) {
+	c.lock.Lock()
+	if c.conn != conn {
+		c.lock.Unlock()
+		return
+	}
+	wasBound, targetID := c.isBound, c.targetID
+	c.conn = nil
+	c.isBound = false
+	c.targetID = ""
+	c.lock.Unlock()
+
+	if wasBound {
+		c.dispatchEvent(enums.OnSessionBreak, WSMessage{
+			Type:     enums.MsgTypeBreak,
+			ClientID: c.GetClientID(),
+			TargetID: targetID,
+			MsgData:  enums.RetCodeDisconnect.String(),
+		}, nil)
+	}
+}
+
+// handleMessage returns true when the relay assigned a new clientID.
+func (c *Client) handleMessage(message WSMessage) bool {
+	switch message.Type {
+	case enums.MsgTypeBind:
+		return c.handleBind(message)
+	case enums.MsgTypeMessage:
+		c.handleMsg(message)
+	case enums.MsgTypeHeartBeat:
+		c.dispatchEvent(enums.OnSessionHeartBeat, message, nil)
+	case enums.MsgTypeBreak:
+		c.lock.Lock()
+		c.isBound = false
+		c.targetID = ""
+		c.lock.Unlock()
+		c.dispatchEvent(enums.OnSessionBreak, message, nil)
+	case enums.MsgTypeError:
+		zap.L().Error("Received error message", zap.String("message", message.MsgData))
+		c.dispatchEvent(enums.OnSessionError, message, nil)
+	}
+	return false
+}
+
+func (c *Client) handleBind(message WSMessage) bool {
+	switch message.MsgData {
+	case enums.MsgHeadTargetID.String():
+		c.lock.Lock()
+		c.clientID = message.ClientID
+		c.targetID = ""
+		c.isBound = false
+		c.lock.Unlock()
+
+		c.dispatchEvent(enums.OnSessionIDAssigned, message, message.ClientID)
+		return true
+	case enums.RetCodeSuccess.String():
+		c.lock.Lock()
+		c.targetID = message.TargetID
+		c.isBound = true
+		c.lock.Unlock()
+
+		c.dispatchEvent(enums.OnSessionBind, message, message.TargetID)
+	default:
+		zap.L().Error("Failed to bind", zap.String("code", message.MsgData))
+		c.dispatchEvent(enums.OnSessionError, message, nil)
+	}
+	return false
+}
+
+func (c *Client) handleMsg(message WSMessage) {
+	msgHead := enums.GetMsgHead(strings.Split(message.MsgData, "-")[0])
+	c.dispatchEvent(enums.OnSessionMessageReceived, message, msgHead)
+
+	switch msgHead {
+	case enums.MsgHeadStrength:
+		result, err := ParseStrengthData(message.MsgData)
+		if err != nil {
+			zap.L().Error("Invalid strength data", zap.Error(err))
+			return
+		}
+		strengthData := StrengthData{
+			StrengthA:    result[0],
+			StrengthB:    result[1],
+			MaxStrengthA: result[2],
+			MaxStrengthB: result[3],
+		}
+		c.lock.Lock()
+		c.strengthData = strengthData
+		c.lock.Unlock()
+		c.dispatchEvent(enums.OnSessionStrengthChange, message, strengthData)
+	case enums.MsgHeadFeedback:
+		buttonIndex, err := ParseFeedbackData(message.MsgData)
+		if err != nil {
+			zap.L().Error("Invalid feedback data", zap.Error(err))
+			return
+		}
+		c.dispatchEvent(enums.OnSessionFeedback, message, buttonIndex)
+	}
+}
+
+func (c *Client) sendMsg(msgData string) error {
+	c.lock.RLock()
+	conn := c.conn
+	bound := c.isBound
+	msg := WSMessage{
+		Type:     enums.MsgTypeMessage,
+		ClientID: c.clientID,
+		TargetID: c.targetID,
+		MsgData:  msgData,
+	}
+	c.lock.RUnlock()
+
+	if !bound || conn == nil {
+		return NotBindError{
+			Message: "Client is not bound",
+		}
+	}
+
+	jsonMsg, err := json.Marshal(msg)
+	if err != nil {
+		return err
+	}
+	if len(jsonMsg) > 1950 {
+		return TooLongMessageError{
+			Message: "message length larger than 1950",
+		}
+	}
+
+	c.writeLock.Lock()
+	defer c.writeLock.Unlock()
+	return conn.WriteMessage(websocket.TextMessage, jsonMsg)
+}
+
+func (c *Client) SetStrength(channel enums.ChannelType, action enums.StrengthAction, strength int) error {
+	return c.sendMsg(FormatStrengthMsg(channel, action, strength))
+}
+
+func (c *Client) ClearPulse(channel enums.ChannelType) error {
+	return c.sendMsg(FormatClearMsg(channel))
+}
+
+func (c *Client) AddPulse(channel enums.ChannelType, waveform PulseWaveform) error {
+	if !c.IsBound() {
+		return NotBindError{
+			Message: "Client is not bound",
+		}
+	}
+	if len(waveform) == 0 {
+		return nil
+	}
+
+	msgData, err := FormatPulseMsg(channel, waveform)
+	if err != nil {
+		return err
+	}
+	return c.sendMsg(msgData)
+}
+
+func (c *Client) IsBound() bool {
+	c.lock.RLock()
+	defer c.lock.RUnlock()
+
+	return c.conn != nil && c.isBound
+}
+
+func (c *Client) GetStrengthData() StrengthData {
+	c.lock.RLock()
+	defer c.lock.RUnlock()
+
+	return c.strengthData
+}
+
+func (c *Client) GetClientID() string {
+	c.lock.RLock()
+	defer c.lock.RUnlock()
+
+	return c.clientID
+}
+
+// GetQRCodeContent is only valid after OnSessionIDAssigned, the relay assigns
+// a new clientID on every reconnect.
+func (c *Client) GetQRCodeContent() string {
+	uri := strings.TrimSuffix(c.config.RelayURL, "/")
+	return fmt.Sprintf("https://www.dungeon-lab.com/app-download.php#DGLAB-SOCKET#%s/%s", uri, c.GetClientID())
+}
+
+func (c *Client) RegisterCallback(eventType enums.SessionEvent, callback SessionCallback) {
+	c.callbackLock.Lock()
+	defer c.callbackLock.Unlock()
+
+	c.callbacks[eventType] = append(c.callbacks[eventType], callback)
+}
+
+func (c *Client) dispatchEvent(eventType enums.SessionEvent, message WSMessage, data any) {
+	c.callbackLock.RLock()
+	callbacks := c.callbacks[eventType]
+	c.callbackLock.RUnlock()
+
+	for _, callback := range callbacks {
+		callbackData := CallbackData[any]{
+			Message:      &message,
+			CallbackData: data,
+		}
+		go callback(c, callbackData)
+	}
+}
+
+func NewCoyoteClient(config *Config) *Client {
+	return &Client{
+		config:    config,
+		callbacks: make(map[enums.SessionEvent][]SessionCallback),
+	}
+}
@@ -0,0 +1,235 @@
+package coyote
+
+import (
+	"IsaacCoyote/pkg/coyote/enums"
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/gorilla/websocket"
+)
+
+func startRelay(t *testing.T) (*Relay, string, func()) {
+	t.Helper()
+	port := freePort(t)
+	r := NewCoyoteRelay(&Config{Port: port})
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error, 1)
+	go func() {
+		done <- r.Run(ctx)
+	}()
+	waitFor(t, "relay start", r.IsRunning)
+	return r, fmt.Sprintf("ws://127.0.0.1:%d", port), func() {
+		cancel()
+		if err := <-done; err != nil {
+			t.Errorf("Run: %v", err)
+		}
+	}
+}
+
+func startClient(t *testing.T, config *Config) (*Client, chan string, func()) {
+	t.Helper()
+	client := NewCoyoteClient(config)
+	assigned := make(chan string, 8)
+	client.RegisterCallback(enums.OnSessionIDAssigned, func(_ Controller, callbackData CallbackData[any]) {
+		assigned <- callbackData.CallbackData.(string)
+	})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error, 1)
+	go func() {
+		done <- client.Run(ctx)
+	}()
+	return client, assigned, func() {
+		cancel()
+		if err := <-done; err != nil {
+			t.Errorf("Run: %v", err)
+		}
+	}
+}
+
+func receive[T any](t *testing.T, what string, ch <-chan T) T {
+	t.Helper()
+	select {
+	case v := <-ch:
+		return v
+	case <-time.After(5 * time.Second):
+		t.Fatalf("timed out waiting for %s", what)
+	}
+	var zero T
+	return zero
+}
+
+// bindApp connects an app to the relay and binds it to clientID.
+func bindApp(t *testing.T, relayURL string, clientID string) (*fakeApp, string) {
+	t.Helper()
+	app, err := dialApp(relayURL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assigned, err := app.expect(enums.MsgTypeBind, enums.MsgHeadTargetID.String())
+	if err != nil {
+		t.Fatal(err)
+	}
+	appID := assigned.ClientID
+	err = app.send(WSMessage{
+		Type:     enums.MsgTypeBind,
+		ClientID: clientID,
+		TargetID: appID,
+		MsgData:  enums.MsgHeadDGLab.String(),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = app.expect(enums.MsgTypeBind, enums.RetCodeSuccess.String())
+	if err != nil {
+		t.Fatal(err)
+	}
+	return app, appID
+}
+
+func TestClientBind(t *testing.T) {
+	_, relayURL, stopRelay := startRelay(t)
+	defer stopRelay()
+	client, assigned, stopClient := startClient(t, &Config{RelayURL: relayURL})
+	defer stopClient()
+
+	clientID := receive(t, "clientID", assigned)
+	if client.GetClientID() != clientID {
+		t.Fatalf("GetClientID = %q, want %q", client.GetClientID(), clientID)
+	}
+	if !strings.HasSuffix(client.GetQRCodeContent(), relayURL+"/"+clientID) {
+		t.Fatalf("GetQRCodeContent = %q", client.GetQRCodeContent())
+	}
+	if err := client.SetStrength(enums.ChannelTypeA, enums.StrengthActionSetTo, 5); err == nil {
+		t.Fatal("SetStrength succeeded before bind")
+	}
+
+	app, appID := bindApp(t, relayURL, clientID)
+	defer app.conn.Close()
+	waitFor(t, "bind", client.IsBound)
+
+	err := app.send(WSMessage{
+		Type:     enums.MsgTypeMessage,
+		ClientID: clientID,
+		TargetID: appID,
+		MsgData:  "strength-10+20+100+100",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	waitFor(t, "strength", func() bool {
+		return client.GetStrengthData() == StrengthData{StrengthA: 10, StrengthB: 20, MaxStrengthA: 100, MaxStrengthB: 100}
+	})
+
+	err = client.SetStrength(enums.ChannelTypeA, enums.StrengthActionSetTo, 5)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = app.expect(enums.MsgTypeMessage, FormatStrengthMsg(enums.ChannelTypeA, enums.StrengthActionSetTo, 5))
+	if err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestClientNewClientIDAfterDrop(t *testing.T) {
+	relay, relayURL, stopRelay := startRelay(t)
+	defer stopRelay()
+	client, assigned, stopClient := startClient(t, &Config{
+		RelayURL:          relayURL,
+		ReconnectMinDelay: 10 * time.Millisecond,
+	})
+	defer stopClient()
+	breaks := make(chan struct{}, 8)
+	client.RegisterCallback(enums.OnSessionBreak, func(Controller, CallbackData[any]) {
+		breaks <- struct{}{}
+	})
+
+	firstID := receive(t, "clientID", assigned)
+	app, _ := bindApp(t, relayURL, firstID)
+	defer app.conn.Close()
+	waitFor(t, "bind", client.IsBound)
+
+	// the relay drops the client, the app stays connected
+	relay.lock.RLock()
+	session := relay.clients[firstID]
+	relay.lock.RUnlock()
+	_ = session.Close()
+
+	receive(t, "break", breaks)
+	secondID := receive(t, "new clientID", assigned)
+	if secondID == firstID {
+		t.Fatalf("clientID %q was reused after reconnect", secondID)
+	}
+	if client.GetClientID() != secondID {
+		t.Fatalf("GetClientID = %q, want %q", client.GetClientID(), secondID)
+	}
+	if client.IsBound() {
+		t.Fatal("client is bound after reconnect")
+	}
+}
+
+func TestClientReconnectBackoff(t *testing.T) {
+	const (
+		minDelay = 50 * time.Millisecond
+		maxDelay = 200 * time.Millisecond
+	)
+
+	var lock sync.Mutex
+	var attempts []time.Time
+	upgrader := websocket.Upgrader{}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		lock.Lock()
+		attempts = append(attempts, time.Now())
+		n := len(attempts)
+		lock.Unlock()
+
+		// the first attempts fail, the fifth connects and is dropped right after the handshake
+		if n < 5 {
+			http.Error(w, "unavailable", http.StatusServiceUnavailable)
+			return
+		}
+		conn, err := upgrader.Upgrade(w, r, nil)
+		if err != nil {
+			return
+		}
+		_ = conn.WriteJSON(WSMessage{
+			Type:     enums.MsgTypeBind,
+			ClientID: fmt.Sprintf("client-%d", n),
+			MsgData:  enums.MsgHeadTargetID.String(),
+		})
+		_ = conn.Close()
+	}))
+	defer server.Close()
+
+	_, _, stopClient := startClient(t, &Config{
+		RelayURL:          "ws" + strings.TrimPrefix(server.URL, "http"),
+		ReconnectMinDelay: minDelay,
+		ReconnectMaxDelay: maxDelay,
+	})
+	waitFor(t, "reconnect after the established connection", func() bool {
+		lock.Lock()
+		defer lock.Unlock()
+		return len(attempts) >= 6
+	})
+	stopClient()
+
+	lock.Lock()
+	defer lock.Unlock()
+	want := []time.Duration{minDelay, 2 * minDelay, maxDelay, maxDelay, minDelay}
+	for i, delay := range want {
+		gap := attempts[i+1].Sub(attempts[i])
+		if gap < delay {
+			t.Errorf("attempt %d came after %v, want at least %v", i+2, gap, delay)
+		}
+	}
+	// an established connection resets the backoff
+	if gap := attempts[5].Sub(attempts[4]); gap >= maxDelay {
+		t.Errorf("reconnect after an established connection took %v, want about %v", gap, minDelay)
+	}
+}
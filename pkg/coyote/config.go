@@ -1,6 +1,91 @@
 package coyote
 
+import "time"
+
+const (
+	defaultShutdownTimeout = 5 * time.Second
+
+	defaultHeartbeatInterval = 15 * time.Second
+	defaultStaleTimeout      = 35 * time.Second
+	defaultEvictTimeout      = 60 * time.Second
+
+	defaultReconnectMinDelay = time.Second
+	defaultReconnectMaxDelay = 30 * time.Second
+
+	// maxMessageSize leaves room for the json envelope around a 1950 byte message
+	maxMessageSize = 4096
+)
+
 type Config struct {
 	Address string
 	Port    int
+
+	TLS TLSConfig
+
+	// HeartbeatInterval is how often heartbeats are sent to bound apps.
+	HeartbeatInterval time.Duration
+	// StaleTimeout marks a session as unbound when nothing was heard from the app for this long.
+	StaleTimeout time.Duration
+	// EvictTimeout closes the connection of a session that stayed silent for this long.
+	EvictTimeout time.Duration
+
+	// ResumeGrace keeps a dropped session resumable for this long, so the app can
+	// reconnect with the same clientID and the last StrengthData is kept. 0 disables it.
+	ResumeGrace time.Duration
+
+	// RelayURL is the DG-LAB socket relay a Client connects to, e.g. wss://ws.dungeon-lab.cn
+	RelayURL string
+	// ReconnectMinDelay and ReconnectMaxDelay bound the exponential backoff of a Client.
+	ReconnectMinDelay time.Duration
+	ReconnectMaxDelay time.Duration
+}
+
+// TLSConfig enables wss://. A self-signed certificate is generated
+// at startup when CertFile or KeyFile is empty.
+type TLSConfig struct {
+	Enabled  bool
+	CertFile string
+	KeyFile  string
+}
+
+func (c *Config) scheme() string {
+	if c.TLS.Enabled {
+		return "wss"
+	}
+	return "ws"
+}
+
+func (c *Config) heartbeatInterval() time.Duration {
+	if c.HeartbeatInterval <= 0 {
+		return defaultHeartbeatInterval
+	}
+	return c.HeartbeatInterval
+}
+
+func (c *Config) staleTimeout() time.Duration {
+	if c.StaleTimeout <= 0 {
+		return defaultStaleTimeout
+	}
+	return c.StaleTimeout
+}
+
+func (c *Config) evictTimeout() time.Duration {
+	if c.EvictTimeout <= 0 {
+		return defaultEvictTimeout
+	}
+	return c.EvictTimeout
+}
+
+func (c *Config) reconnectMinDelay() time.Duration {
+	if c.ReconnectMinDelay <= 0 {
+		return defaultReconnectMinDelay
+	}
+	return c.ReconnectMinDelay
+}
+
+func (c *Config) reconnectMaxDelay() time.Duration {
+	if c.ReconnectMaxDelay <= 0 {
+		return defaultReconnectMaxDelay
+	}
+	return max(c.ReconnectMaxDelay, c.reconnectMinDelay())
 }
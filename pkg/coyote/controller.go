@@ -0,0 +1,20 @@
+package coyote
+
+import "IsaacCoyote/pkg/coyote/enums"
+
+type SessionCallback func(controller Controller, callbackData CallbackData[any])
+
+// Controller drives one DG-LAB app, either through a Session of the embedded
+// server or through a Client connected to a remote relay.
+type Controller interface {
+	GetClientID() string
+	GetQRCodeContent() string
+	IsBound() bool
+
+	SetStrength(channel enums.ChannelType, action enums.StrengthAction, strength int) error
+	AddPulse(channel enums.ChannelType, waveform PulseWaveform) error
+	ClearPulse(channel enums.ChannelType) error
+	GetStrengthData() StrengthData
+
+	RegisterCallback(eventType enums.SessionEvent, callback SessionCallback)
+}
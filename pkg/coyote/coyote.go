@@ -2,58 +2,128 @@ package coyote
 
 import (
 	"IsaacCoyote/pkg/coyote/enums"
+	"context"
 	"encoding/json"
-	"fmt"
 	"github.com/google/uuid"
 	"github.com/olahol/melody"
 	"go.uber.org/zap"
+	"sync"
+	"time"
 )
 
 type Coyote struct {
 	config *Config
 
-	wsServer  *Server
-	callbacks map[enums.ServerEvent][]func(callbackData CallbackData[any])
-	sessions  map[string]*Session //map[clientID]*Session
+	wsServer *Server
+	sessions *SessionRegistry
+
+	callbackLock sync.RWMutex
+	callbacks    map[enums.ServerEvent][]func(callbackData CallbackData[any])
 }
 
 func (c *Coyote) IsRunning() bool {
-	if c.wsServer == nil {
-		return false
-	}
-	return c.wsServer.IsRunning
+	return c.wsServer.IsRunning()
 }
 
-func (c *Coyote) Run() error {
-	if c.wsServer == nil {
-		c.wsServer = NewCoyoteServer(c.config, c.connectHandler, c.disconnectHandler, c.msgHandler)
-	}
-	if c.wsServer.IsRunning {
+// Run blocks until the server fails, Shutdown is called or ctx is cancelled.
+// On cancellation every bound session receives a break before the server stops.
+func (c *Coyote) Run(ctx context.Context) error {
+	if c.wsServer.IsRunning() {
 		return AlreadyRunningError{
 			Message: "  already running",
 		}
 	}
-	return c.wsServer.Run()
+
+	// the server is stopped by Shutdown, so it must not race us on ctx
+	serverCtx, cancel := context.WithCancel(context.WithoutCancel(ctx))
+	var wg sync.WaitGroup
+	defer func() {
+		cancel()
+		wg.Wait()
+	}()
+
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		c.superviseHeartbeat(serverCtx)
+	}()
+
+	errCh := make(chan error, 1)
+	go func() {
+		errCh <- c.wsServer.Run(serverCtx)
+	}()
+
+	select {
+	case err := <-errCh:
+		return err
+	case <-ctx.Done():
+		shutdownCtx, cancelShutdown := context.WithTimeout(context.Background(), defaultShutdownTimeout)
+		defer cancelShutdown()
+		err := c.Shutdown(shutdownCtx)
+		<-errCh
+		return err
+	}
 }
 
-func (c *Coyote) GetSessionByClientID(clientID string) (*Session, error) {
-	session := c.sessions[clientID]
-	if session == nil {
-		return nil, SessionNotFoundError{
-			Message: fmt.Sprintf("Session not found for clientID: %s", clientID),
+// Shutdown sends break to all bound sessions and stops the websocket server.
+func (c *Coyote) Shutdown(ctx context.Context) error {
+	c.sessions.Range(func(session *Session) bool {
+		if session.IsBound() {
+			session.Disconnect()
 		}
+		return true
+	})
+	return c.wsServer.Shutdown(ctx)
+}
+
+func (c *Coyote) superviseHeartbeat(ctx context.Context) {
+	ticker := time.NewTicker(c.config.heartbeatInterval())
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+
+		c.sessions.Range(func(session *Session) bool {
+			session.superviseHeartbeat()
+			return true
+		})
 	}
-	return session, nil
+}
+
+func (c *Coyote) GetSessionByClientID(clientID string) (*Session, error) {
+	return c.sessions.Get(clientID)
+}
+
+// Sessions returns a snapshot of all sessions.
+func (c *Coyote) Sessions() []*Session {
+	return c.sessions.List()
+}
+
+// RemoveSession disconnects the session and forgets its clientID.
+func (c *Coyote) RemoveSession(clientID string) error {
+	session, err := c.sessions.Remove(clientID)
+	if err != nil {
+		return err
+	}
+	session.Disconnect()
+	return nil
 }
 
 func (c *Coyote) RegisterCallback(eventType enums.ServerEvent, callback func(callbackData CallbackData[any])) {
+	c.callbackLock.Lock()
+	defer c.callbackLock.Unlock()
+
 	c.callbacks[eventType] = append(c.callbacks[eventType], callback)
 }
 
 func (c *Coyote) NewSession() *Session {
 	clientID := uuid.New().String()
 	session := NewCoyoteSession(clientID, c.config)
-	c.sessions[clientID] = session
+	c.sessions.Add(session)
 	return session
 }
 
@@ -87,8 +157,8 @@ func (c *Coyote) closeWithMsg(s *melody.Session, msg WSMessage) error {
 
 func (c *Coyote) connectHandler(s *melody.Session) {
 	clientID := s.Request.RequestURI[1:]
-	session := c.sessions[clientID]
-	if session == nil {
+	session, err := c.sessions.Get(clientID)
+	if err != nil {
 		errMsg := WSMessage{
 			Type:     enums.MsgTypeError,
 			ClientID: clientID,
@@ -107,6 +177,7 @@ func (c *Coyote) connectHandler(s *melody.Session) {
 		}
 		c.closeWithMsg(s, errMsg)
 		_ = s.Close()
+		return
 	}
 
 	c.dispatchEvent(enums.OnConnect, clientID)
@@ -116,7 +187,7 @@ func (c *Coyote) connectHandler(s *melody.Session) {
 		ClientID: clientID,
 		MsgData:  enums.MsgHeadTargetID.String(),
 	}
-	err := session.SendMessage(bindMsg)
+	err = session.SendMessage(bindMsg)
 	if err != nil {
 		zap.L().Error("failed to bind", zap.Error(err))
 	}
@@ -127,10 +198,28 @@ func (c *Coyote) disconnectHandler(s *melody.Session) {
 	if !exists {
 		return
 	}
-	c.sessions[clientId.(string)].Disconnect()
+	session, err := c.sessions.Get(clientId.(string))
+	if err != nil || !session.ownsWSSession(s) {
+		return
+	}
+	if c.config.ResumeGrace > 0 {
+		session.suspend()
+	} else {
+		session.Disconnect()
+	}
 	c.dispatchEvent(enums.OnDisconnect, clientId.(string))
 }
 
+func (c *Coyote) pongHandler(s *melody.Session) {
+	clientID, exists := s.Get("clientID")
+	if !exists {
+		return
+	}
+	if session, err := c.sessions.Get(clientID.(string)); err == nil && session.ownsWSSession(s) {
+		session.touch()
+	}
+}
+
 func (c *Coyote) msgHandler(s *melody.Session, rawMsg []byte) {
 	message := WSMessage{}
 	err := json.Unmarshal(rawMsg, &message)
@@ -144,11 +233,12 @@ func (c *Coyote) msgHandler(s *melody.Session, rawMsg []byte) {
 		return
 	}
 
-	session := c.sessions[message.ClientID]
-	if session == nil {
+	session, err := c.sessions.Get(message.ClientID)
+	if err != nil || !session.ownsWSSession(s) {
 		zap.L().Error("Session not found", zap.String("clientID", message.ClientID))
 		return
 	}
+	session.touch()
 	c.dispatchEvent(enums.OnMessageReceived, session.clientID)
 
 	switch message.Type {
@@ -178,7 +268,10 @@ func (c *Coyote) msgHandler(s *melody.Session, rawMsg []byte) {
 }
 
 func (c *Coyote) dispatchEvent(eventType enums.ServerEvent, clientID string) {
+	c.callbackLock.RLock()
 	callbacks := c.callbacks[eventType]
+	c.callbackLock.RUnlock()
+
 	for _, callback := range callbacks {
 		callbackData := CallbackData[any]{
 			CallbackData: clientID,
@@ -188,10 +281,11 @@ func (c *Coyote) dispatchEvent(eventType enums.ServerEvent, clientID string) {
 }
 
 func NewCoyote(config *Config) *Coyote {
-	return &Coyote{
-		wsServer:  nil,
+	c := &Coyote{
 		config:    config,
 		callbacks: make(map[enums.ServerEvent][]func(callbackData CallbackData[any])),
-		sessions:  make(map[string]*Session),
+		sessions:  NewSessionRegistry(),
 	}
+	c.wsServer = NewCoyoteServer(config, c.connectHandler, c.disconnectHandler, c.msgHandler, c.pongHandler)
+	return c
 }
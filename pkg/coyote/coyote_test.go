@@ -0,0 +1,252 @@
+package coyote
+
+import (
+	"IsaacCoyote/pkg/coyote/enums"
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/gorilla/websocket"
+)
+
+func freePort(t *testing.T) int {
+	t.Helper()
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer listener.Close()
+	return listener.Addr().(*net.TCPAddr).Port
+}
+
+func waitFor(t *testing.T, what string, cond func() bool) {
+	t.Helper()
+	deadline := time.Now().Add(5 * time.Second)
+	for !cond() {
+		if time.Now().After(deadline) {
+			t.Fatalf("timed out waiting for %s", what)
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+}
+
+// fakeApp plays the DG-LAB app side of a websocket connection.
+type fakeApp struct {
+	conn     *websocket.Conn
+	messages chan WSMessage
+}
+
+func dialApp(url string) (*fakeApp, error) {
+	conn, _, err := websocket.DefaultDialer.Dial(url, nil)
+	if err != nil {
+		return nil, err
+	}
+	app := &fakeApp{
+		conn:     conn,
+		messages: make(chan WSMessage, 64),
+	}
+	go func() {
+		defer close(app.messages)
+		for {
+			message := WSMessage{}
+			if err := conn.ReadJSON(&message); err != nil {
+				return
+			}
+			app.messages <- message
+		}
+	}()
+	return app, nil
+}
+
+// expect reads until a message of msgType with msgData arrives.
+func (a *fakeApp) expect(msgType enums.MsgType, msgData string) (WSMessage, error) {
+	timeout := time.After(5 * time.Second)
+	for {
+		select {
+		case message, ok := <-a.messages:
+			if !ok {
+				return WSMessage{}, errors.New("connection closed")
+			}
+			if message.Type == msgType && message.MsgData == msgData {
+				return message, nil
+			}
+		case <-timeout:
+			return WSMessage{}, fmt.Errorf("no %s %s message", msgType, msgData)
+		}
+	}
+}
+
+func (a *fakeApp) send(message WSMessage) error {
+	return a.conn.WriteJSON(message)
+}
+
+func startCoyote(t *testing.T, config *Config) (*Coyote, func()) {
+	t.Helper()
+	c := NewCoyote(config)
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error, 1)
+	go func() {
+		done <- c.Run(ctx)
+	}()
+	waitFor(t, "server start", c.IsRunning)
+	return c, func() {
+		cancel()
+		if err := <-done; err != nil {
+			t.Errorf("Run: %v", err)
+		}
+	}
+}
+
+// runApp connects, binds, reports a strength and disconnects one session.
+func runApp(port int, session *Session, appID string) error {
+	app, err := dialApp(fmt.Sprintf("ws://127.0.0.1:%d/%s", port, session.GetClientID()))
+	if err != nil {
+		return err
+	}
+	defer app.conn.Close()
+
+	_, err = app.expect(enums.MsgTypeBind, enums.MsgHeadTargetID.String())
+	if err != nil {
+		return err
+	}
+	err = app.send(WSMessage{
+		Type:     enums.MsgTypeBind,
+		ClientID: session.GetClientID(),
+		TargetID: appID,
+		MsgData:  enums.MsgHeadDGLab.String(),
+	})
+	if err != nil {
+		return err
+	}
+	_, err = app.expect(enums.MsgTypeBind, enums.RetCodeSuccess.String())
+	if err != nil {
+		return err
+	}
+
+	err = app.send(WSMessage{
+		Type:     enums.MsgTypeMessage,
+		ClientID: session.GetClientID(),
+		TargetID: appID,
+		MsgData:  "strength-10+20+100+100",
+	})
+	if err != nil {
+		return err
+	}
+	_, err = app.expect(enums.MsgTypeMessage, FormatStrengthMsg(enums.ChannelTypeA, enums.StrengthActionSetTo, 5))
+	return err
+}
+
+func TestCoyoteConcurrentSessions(t *testing.T) {
+	port := freePort(t)
+	c, stop := startCoyote(t, &Config{Address: "127.0.0.1", Port: port})
+	defer stop()
+
+	const apps = 8
+	sessions := make([]*Session, apps)
+	for i := range sessions {
+		sessions[i] = c.NewSession()
+	}
+
+	var wg sync.WaitGroup
+	errCh := make(chan error, 2*apps)
+	for i, session := range sessions {
+		wg.Add(2)
+		go func() {
+			defer wg.Done()
+			errCh <- runApp(port, session, fmt.Sprintf("app-%d", i))
+		}()
+		// the game drives the session while the app connects and leaves
+		go func() {
+			defer wg.Done()
+			deadline := time.Now().Add(5 * time.Second)
+			for session.GetStrengthData().StrengthA != 10 {
+				if time.Now().After(deadline) {
+					errCh <- fmt.Errorf("session %d: strength was not reported", i)
+					return
+				}
+				_ = session.IsBound()
+				_ = c.IsRunning()
+				time.Sleep(time.Millisecond)
+			}
+			errCh <- session.SetStrength(enums.ChannelTypeA, enums.StrengthActionSetTo, 5)
+		}()
+	}
+	wg.Wait()
+	close(errCh)
+	for err := range errCh {
+		if err != nil {
+			t.Error(err)
+		}
+	}
+
+	for _, session := range sessions {
+		waitFor(t, "disconnect", func() bool {
+			return !session.IsBound()
+		})
+		if got := session.GetStrengthData(); got != (StrengthData{StrengthA: 10, StrengthB: 20, MaxStrengthA: 100, MaxStrengthB: 100}) {
+			t.Errorf("StrengthData = %+v", got)
+		}
+	}
+}
+
+func TestCoyoteRejectsUnknownClientID(t *testing.T) {
+	port := freePort(t)
+	_, stop := startCoyote(t, &Config{Address: "127.0.0.1", Port: port})
+	defer stop()
+
+	app, err := dialApp(fmt.Sprintf("ws://127.0.0.1:%d/unknown", port))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer app.conn.Close()
+
+	_, err = app.expect(enums.MsgTypeError, enums.RetCodeReceiverOffline.String())
+	if err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestServerRunsOnce(t *testing.T) {
+	port := freePort(t)
+	c, stop := startCoyote(t, &Config{Address: "127.0.0.1", Port: port})
+	defer stop()
+
+	var wg sync.WaitGroup
+	for range 4 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			err := c.wsServer.Run(context.Background())
+			if !errors.As(err, &AlreadyRunningError{}) {
+				t.Errorf("Run = %v, want AlreadyRunningError", err)
+			}
+		}()
+	}
+	wg.Wait()
+}
+
+func TestRelayStartStop(t *testing.T) {
+	r := NewCoyoteRelay(&Config{Port: freePort(t)})
+	if r.IsRunning() {
+		t.Fatal("relay running before Run")
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error, 1)
+	go func() {
+		done <- r.Run(ctx)
+	}()
+	waitFor(t, "relay start", r.IsRunning)
+
+	cancel()
+	if err := <-done; err != nil {
+		t.Fatalf("Run: %v", err)
+	}
+	if r.IsRunning() {
+		t.Fatal("relay still running after Run returned")
+	}
+}
@@ -18,4 +18,7 @@ const (
 	OnSessionStrengthChange
 	OnSessionBreak
 	OnSessionError
+	OnSessionSuspend
+	OnSessionResume
+	OnSessionIDAssigned
 )
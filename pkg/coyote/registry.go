@@ -0,0 +1,82 @@
+package coyote
+
+import (
+	"fmt"
+	"sync"
+)
+
+// SessionRegistry is a concurrency-safe map[clientID]*Session.
+type SessionRegistry struct {
+	lock     sync.RWMutex
+	sessions map[string]*Session
+}
+
+func (r *SessionRegistry) Add(session *Session) {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+
+	r.sessions[session.clientID] = session
+}
+
+func (r *SessionRegistry) Get(clientID string) (*Session, error) {
+	r.lock.RLock()
+	defer r.lock.RUnlock()
+
+	session, ok := r.sessions[clientID]
+	if !ok {
+		return nil, SessionNotFoundError{
+			Message: fmt.Sprintf("Session not found for clientID: %s", clientID),
+		}
+	}
+	return session, nil
+}
+
+// Remove deletes the session and returns it, the caller decides whether to disconnect it.
+func (r *SessionRegistry) Remove(clientID string) (*Session, error) {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+
+	session, ok := r.sessions[clientID]
+	if !ok {
+		return nil, SessionNotFoundError{
+			Message: fmt.Sprintf("Session not found for clientID: %s", clientID),
+		}
+	}
+	delete(r.sessions, clientID)
+	return session, nil
+}
+
+// List returns a snapshot of all sessions.
+func (r *SessionRegistry) List() []*Session {
+	r.lock.RLock()
+	defer r.lock.RUnlock()
+
+	sessions := make([]*Session, 0, len(r.sessions))
+	for _, session := range r.sessions {
+		sessions = append(sessions, session)
+	}
+	return sessions
+}
+
+// Range calls fn for every session until fn returns false.
+// fn runs on a snapshot, so it may safely add or remove sessions.
+func (r *SessionRegistry) Range(fn func(session *Session) bool) {
+	for _, session := range r.List() {
+		if !fn(session) {
+			return
+		}
+	}
+}
+
+func (r *SessionRegistry) Len() int {
+	r.lock.RLock()
+	defer r.lock.RUnlock()
+
+	return len(r.sessions)
+}
+
+func NewSessionRegistry() *SessionRegistry {
+	return &SessionRegistry{
+		sessions: make(map[string]*Session),
+	}
+}
@@ -0,0 +1,301 @@
+package coyote
+
+import (
+	"IsaacCoyote/pkg/coyote/enums"
+	"context"
+	"encoding/json"
+	"github.com/google/uuid"
+	"github.com/olahol/melody"
+	"go.uber.org/zap"
+	"sync"
+	"time"
+)
+
+// Relay is a standalone DG-LAB socket server. It binds third-party terminals
+// to apps and forwards msg frames between the bound pair, the same way the
+// official DG-LAB-OPENSOURCE socket backend does.
+type Relay struct {
+	config   *Config
+	wsServer *Server
+
+	lock      sync.RWMutex
+	clients   map[string]*melody.Session // map[id]*melody.Session
+	relations map[string]string          // map[clientID]targetID
+}
+
+func (r *Relay) IsRunning() bool {
+	return r.wsServer.IsRunning()
+}
+
+// Run blocks until the server fails or ctx is cancelled.
+func (r *Relay) Run(ctx context.Context) error {
+	if r.wsServer.IsRunning() {
+		return AlreadyRunningError{
+			Message: "  already running",
+		}
+	}
+
+	heartbeatCtx, cancel := context.WithCancel(ctx)
+	var wg sync.WaitGroup
+	defer func() {
+		cancel()
+		wg.Wait()
+	}()
+
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		r.sendHeartbeats(heartbeatCtx)
+	}()
+
+	return r.wsServer.Run(ctx)
+}
+
+func (r *Relay) Shutdown(ctx context.Context) error {
+	return r.wsServer.Shutdown(ctx)
+}
+
+func (r *Relay) sendHeartbeats(ctx context.Context) {
+	ticker := time.NewTicker(r.config.heartbeatInterval())
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+
+		r.lock.RLock()
+		clients := make(map[string]*melody.Session, len(r.clients))
+		for id, s := range r.clients {
+			clients[id] = s
+		}
+		r.lock.RUnlock()
+
+		for id, s := range clients {
+			r.send(s, WSMessage{
+				Type:     enums.MsgTypeHeartBeat,
+				ClientID: id,
+				TargetID: r.peerOf(id),
+				MsgData:  enums.RetCodeSuccess.String(),
+			})
+		}
+	}
+}
+
+func (r *Relay) connectHandler(s *melody.Session) {
+	id := uuid.New().String()
+	s.Set("clientID", id)
+
+	r.lock.Lock()
+	r.clients[id] = s
+	r.lock.Unlock()
+
+	r.send(s, WSMessage{
+		Type:     enums.MsgTypeBind,
+		ClientID: id,
+		MsgData:  enums.MsgHeadTargetID.String(),
+	})
+}
+
+func (r *Relay) disconnectHandler(s *melody.Session) {
+	rawID, exists := s.Get("clientID")
+	if !exists {
+		return
+	}
+	id := rawID.(string)
+
+	r.lock.Lock()
+	delete(r.clients, id)
+	r.lock.Unlock()
+
+	r.breakRelation(id)
+}
+
+func (r *Relay) msgHandler(s *melody.Session, rawMsg []byte) {
+	rawID, _ := s.Get("clientID")
+	senderID, _ := rawID.(string)
+
+	message := WSMessage{}
+	err := json.Unmarshal(rawMsg, &message)
+	if err != nil {
+		r.send(s, WSMessage{
+			Type:    enums.MsgTypeError,
+			MsgData: enums.RetCodeInvalidMessageFormat.String(),
+		})
+		return
+	}
+
+	switch message.Type {
+	case enums.MsgTypeBind:
+		r.handleBind(s, senderID, message)
+	case enums.MsgTypeMessage:
+		r.forward(s, senderID, message)
+	case enums.MsgTypeBreak:
+		if senderID == message.ClientID || senderID == message.TargetID {
+			r.breakRelation(senderID)
+		}
+	case enums.MsgTypeHeartBeat:
+		// clients may answer our heartbeat, nothing to do
+	default:
+		r.send(s, WSMessage{
+			Type:     enums.MsgTypeError,
+			ClientID: message.ClientID,
+			TargetID: message.TargetID,
+			MsgData:  enums.RetCodeInternalError.String(),
+		})
+	}
+}
+
+func (r *Relay) handleBind(s *melody.Session, senderID string, message WSMessage) {
+	reply := WSMessage{
+		Type:     enums.MsgTypeBind,
+		ClientID: message.ClientID,
+		TargetID: message.TargetID,
+	}
+
+	r.lock.Lock()
+	client, clientOnline := r.clients[message.ClientID]
+	_, targetOnline := r.clients[message.TargetID]
+	switch {
+	case !clientOnline || !targetOnline || message.ClientID == message.TargetID,
+		senderID != message.ClientID && senderID != message.TargetID:
+		reply.MsgData = enums.RetCodeTargetClientNotFound.String()
+	case r.isRelatedLocked(message.ClientID) || r.isRelatedLocked(message.TargetID):
+		reply.MsgData = enums.RetCodeClientIDAlreadyUsed.String()
+	default:
+		r.relations[message.ClientID] = message.TargetID
+		reply.MsgData = enums.RetCodeSuccess.String()
+	}
+	r.lock.Unlock()
+
+	r.send(s, reply)
+	if reply.MsgData == enums.RetCodeSuccess.String() && client != s {
+		r.send(client, reply)
+	}
+}
+
+func (r *Relay) forward(s *melody.Session, senderID string, message WSMessage) {
+	r.lock.RLock()
+	related := r.relations[message.ClientID] == message.TargetID
+	client := r.clients[message.ClientID]
+	target := r.clients[message.TargetID]
+	r.lock.RUnlock()
+
+	if !related || (senderID != message.ClientID && senderID != message.TargetID) {
+		r.send(s, WSMessage{
+			Type:     enums.MsgTypeBind,
+			ClientID: message.ClientID,
+			TargetID: message.TargetID,
+			MsgData:  enums.RetCodeNotBound.String(),
+		})
+		return
+	}
+	if len(message.MsgData) > 1950 {
+		r.send(s, WSMessage{
+			Type:     enums.MsgTypeError,
+			ClientID: message.ClientID,
+			TargetID: message.TargetID,
+			MsgData:  enums.RetCodeMessageTooLong.String(),
+		})
+		return
+	}
+
+	receiver := target
+	if senderID == message.TargetID {
+		receiver = client
+	}
+	if receiver == nil || receiver.IsClosed() {
+		r.send(s, WSMessage{
+			Type:     enums.MsgTypeError,
+			ClientID: message.ClientID,
+			TargetID: message.TargetID,
+			MsgData:  enums.RetCodeReceiverOffline.String(),
+		})
+		return
+	}
+	r.send(receiver, message)
+}
+
+// breakRelation removes the relation of id and tells its peer.
+func (r *Relay) breakRelation(id string) {
+	r.lock.Lock()
+	clientID, targetID := id, r.relations[id]
+	if targetID == "" {
+		for c, t := range r.relations {
+			if t == id {
+				clientID, targetID = c, t
+				break
+			}
+		}
+	}
+	if targetID == "" {
+		r.lock.Unlock()
+		return
+	}
+	delete(r.relations, clientID)
+	peer := r.clients[clientID]
+	if clientID == id {
+		peer = r.clients[targetID]
+	}
+	r.lock.Unlock()
+
+	if peer != nil {
+		r.send(peer, WSMessage{
+			Type:     enums.MsgTypeBreak,
+			ClientID: clientID,
+			TargetID: targetID,
+			MsgData:  enums.RetCodeDisconnect.String(),
+		})
+	}
+}
+
+func (r *Relay) peerOf(id string) string {
+	r.lock.RLock()
+	defer r.lock.RUnlock()
+
+	if targetID, ok := r.relations[id]; ok {
+		return targetID
+	}
+	for clientID, targetID := range r.relations {
+		if targetID == id {
+			return clientID
+		}
+	}
+	return ""
+}
+
+func (r *Relay) isRelatedLocked(id string) bool {
+	if _, ok := r.relations[id]; ok {
+		return true
+	}
+	for _, targetID := range r.relations {
+		if targetID == id {
+			return true
+		}
+	}
+	return false
+}
+
+func (r *Relay) send(s *melody.Session, msg WSMessage) {
+	jsonMsg, err := json.Marshal(msg)
+	if err != nil {
+		zap.L().Error("failed to marshal message", zap.Error(err))
+		return
+	}
+	err = s.Write(jsonMsg)
+	if err != nil {
+		zap.L().Error("failed to send message", zap.Error(err))
+	}
+}
+
+func NewCoyoteRelay(config *Config) *Relay {
+	r := &Relay{
+		config:    config,
+		clients:   make(map[string]*melody.Session),
+		relations: make(map[string]string),
+	}
+	r.wsServer = NewCoyoteServer(config, r.connectHandler, r.disconnectHandler, r.msgHandler, func(*melody.Session) {})
+	return r
+}
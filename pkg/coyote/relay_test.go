@@ -0,0 +1,175 @@
+package coyote
+
+import (
+	"IsaacCoyote/pkg/coyote/enums"
+	"strings"
+	"testing"
+
+	"github.com/gorilla/websocket"
+)
+
+// connectToRelay connects a terminal or app and returns it with the ID the relay assigned.
+func connectToRelay(t *testing.T, relayURL string) (*fakeApp, string) {
+	t.Helper()
+	conn, err := dialApp(relayURL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() {
+		_ = conn.conn.Close()
+	})
+	assigned, err := conn.expect(enums.MsgTypeBind, enums.MsgHeadTargetID.String())
+	if err != nil {
+		t.Fatal(err)
+	}
+	return conn, assigned.ClientID
+}
+
+func mustSend(t *testing.T, conn *fakeApp, message WSMessage) {
+	t.Helper()
+	if err := conn.send(message); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func mustExpect(t *testing.T, conn *fakeApp, msgType enums.MsgType, msgData string) WSMessage {
+	t.Helper()
+	message, err := conn.expect(msgType, msgData)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return message
+}
+
+// bindPair connects a terminal and an app and binds them, the app sends the bind like the DG-LAB app does.
+func bindPair(t *testing.T, relayURL string) (terminal *fakeApp, terminalID string, app *fakeApp, appID string) {
+	t.Helper()
+	terminal, terminalID = connectToRelay(t, relayURL)
+	app, appID = connectToRelay(t, relayURL)
+	mustSend(t, app, WSMessage{
+		Type:     enums.MsgTypeBind,
+		ClientID: terminalID,
+		TargetID: appID,
+		MsgData:  enums.MsgHeadDGLab.String(),
+	})
+	mustExpect(t, app, enums.MsgTypeBind, enums.RetCodeSuccess.String())
+	mustExpect(t, terminal, enums.MsgTypeBind, enums.RetCodeSuccess.String())
+	return terminal, terminalID, app, appID
+}
+
+func TestRelayForward(t *testing.T) {
+	_, relayURL, stopRelay := startRelay(t)
+	defer stopRelay()
+	terminal, terminalID, app, appID := bindPair(t, relayURL)
+
+	strength := FormatStrengthMsg(enums.ChannelTypeA, enums.StrengthActionSetTo, 5)
+	mustSend(t, terminal, WSMessage{
+		Type:     enums.MsgTypeMessage,
+		ClientID: terminalID,
+		TargetID: appID,
+		MsgData:  strength,
+	})
+	forwarded := mustExpect(t, app, enums.MsgTypeMessage, strength)
+	if forwarded.ClientID != terminalID || forwarded.TargetID != appID {
+		t.Fatalf("forwarded %+v, want clientId %s and targetId %s", forwarded, terminalID, appID)
+	}
+
+	mustSend(t, app, WSMessage{
+		Type:     enums.MsgTypeMessage,
+		ClientID: terminalID,
+		TargetID: appID,
+		MsgData:  "strength-5+0+100+100",
+	})
+	mustExpect(t, terminal, enums.MsgTypeMessage, "strength-5+0+100+100")
+
+	// the peer is told once one side leaves
+	_ = app.conn.Close()
+	mustExpect(t, terminal, enums.MsgTypeBreak, enums.RetCodeDisconnect.String())
+}
+
+func TestRelayErrors(t *testing.T) {
+	relay, relayURL, stopRelay := startRelay(t)
+	defer stopRelay()
+	terminal, terminalID, _, appID := bindPair(t, relayURL)
+	other, otherID := connectToRelay(t, relayURL)
+
+	tests := []struct {
+		name    string
+		prepare func()
+		sender  *fakeApp
+		message WSMessage
+		msgType enums.MsgType
+		retCode enums.RetCode
+	}{
+		{
+			name:    "bind to an ID that is already bound",
+			sender:  other,
+			message: WSMessage{Type: enums.MsgTypeBind, ClientID: terminalID, TargetID: otherID, MsgData: enums.MsgHeadDGLab.String()},
+			msgType: enums.MsgTypeBind,
+			retCode: enums.RetCodeClientIDAlreadyUsed,
+		},
+		{
+			name:    "bind to an unknown ID",
+			sender:  other,
+			message: WSMessage{Type: enums.MsgTypeBind, ClientID: "unknown", TargetID: otherID, MsgData: enums.MsgHeadDGLab.String()},
+			msgType: enums.MsgTypeBind,
+			retCode: enums.RetCodeTargetClientNotFound,
+		},
+		{
+			name:    "message to an ID that is not bound to the sender",
+			sender:  other,
+			message: WSMessage{Type: enums.MsgTypeMessage, ClientID: otherID, TargetID: appID, MsgData: "strength-1+1+100+100"},
+			msgType: enums.MsgTypeBind,
+			retCode: enums.RetCodeNotBound,
+		},
+		{
+			name:    "message for a bound pair from a third client",
+			sender:  other,
+			message: WSMessage{Type: enums.MsgTypeMessage, ClientID: terminalID, TargetID: appID, MsgData: "strength-1+1+100+100"},
+			msgType: enums.MsgTypeBind,
+			retCode: enums.RetCodeNotBound,
+		},
+		{
+			name:    "message longer than 1950",
+			sender:  terminal,
+			message: WSMessage{Type: enums.MsgTypeMessage, ClientID: terminalID, TargetID: appID, MsgData: "pulse-A:" + strings.Repeat("0", 1950)},
+			msgType: enums.MsgTypeError,
+			retCode: enums.RetCodeMessageTooLong,
+		},
+		{
+			// the app dropped before its disconnect was handled, the relation still exists
+			name: "receiver offline",
+			prepare: func() {
+				relay.lock.Lock()
+				delete(relay.clients, appID)
+				relay.lock.Unlock()
+			},
+			sender:  terminal,
+			message: WSMessage{Type: enums.MsgTypeMessage, ClientID: terminalID, TargetID: appID, MsgData: "strength-1+1+100+100"},
+			msgType: enums.MsgTypeError,
+			retCode: enums.RetCodeReceiverOffline,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if tt.prepare != nil {
+				tt.prepare()
+			}
+			mustSend(t, tt.sender, tt.message)
+			mustExpect(t, tt.sender, tt.msgType, tt.retCode.String())
+		})
+	}
+}
+
+func TestRelayInvalidMessage(t *testing.T) {
+	_, relayURL, stopRelay := startRelay(t)
+	defer stopRelay()
+	conn, _ := connectToRelay(t, relayURL)
+
+	err := conn.conn.WriteMessage(websocket.TextMessage, []byte("strength-1+1+100+100"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	mustExpect(t, conn, enums.MsgTypeError, enums.RetCodeInvalidMessageFormat.String())
+}
@@ -2,25 +2,36 @@ package coyote
 
 import (
 	"IsaacCoyote/pkg/coyote/enums"
+	"context"
 	"encoding/json"
 	"fmt"
 	"github.com/olahol/melody"
 	"go.uber.org/zap"
 	"strings"
+	"sync"
 	"time"
 )
 
 type Session struct {
-	wsSession *melody.Session
-	config    *Config
-	clientID  string
-	targetID  string
-
-	strengthData StrengthData
-
+	config   *Config
+	clientID string
+
+	// lock guards the connection state below, which is written from
+	// websocket goroutines and read from game tickers.
+	lock              sync.RWMutex
+	wsSession         *melody.Session
+	targetID          string
+	strengthData      StrengthData
 	lastHeartbeatTime time.Time
-	callbacks         map[enums.SessionEvent][]func(s *Session, callbackData CallbackData[any])
 	isBound           bool
+	isStale           bool
+	suspendedAt       time.Time
+
+	// writeLock serializes writes so messages reach the app in call order.
+	writeLock sync.Mutex
+
+	callbackLock sync.RWMutex
+	callbacks    map[enums.SessionEvent][]SessionCallback
 }
 
 func (s *Session) handleBind(message WSMessage) error {
@@ -31,30 +42,152 @@ func (s *Session) handleBind(message WSMessage) error {
 		Type:     enums.MsgTypeBind,
 	}
 
-	if s.IsBound() {
+	s.lock.Lock()
+	if s.isBoundLocked() {
+		s.lock.Unlock()
 		bindMsg.MsgData = enums.RetCodeClientIDAlreadyUsed.String()
 		return s.SendMessage(bindMsg)
 	}
-
+	// claim the bind before sending so a concurrent bind is rejected
+	resumed := s.isResumableLocked()
+	if !resumed {
+		s.strengthData = StrengthData{}
+	}
+	s.suspendedAt = time.Time{}
 	s.targetID = message.TargetID
+	s.isBound = true
+	strengthData := s.strengthData
+	s.lock.Unlock()
+
 	err := s.SendMessage(bindMsg)
 	if err != nil {
 		zap.L().Error("Failed to Bind", zap.Error(err))
+		s.lock.Lock()
+		s.isBound = false
+		s.targetID = ""
+		s.lock.Unlock()
 		return err
 	}
 
-	s.isBound = true
-	s.dispatchEvent(enums.OnSessionBind, message, s.targetID)
+	s.dispatchEvent(enums.OnSessionBind, message, message.TargetID)
+	if resumed {
+		zap.L().Info("Session resumed", zap.String("clientID", s.clientID))
+		s.dispatchEvent(enums.OnSessionResume, message, strengthData)
+	}
 	return nil
 }
 
+// suspend keeps the session resumable after its connection dropped.
+func (s *Session) suspend() {
+	s.lock.Lock()
+	if !s.isBound {
+		s.lock.Unlock()
+		return
+	}
+	s.isBound = false
+	s.suspendedAt = time.Now()
+	targetID := s.targetID
+	s.lock.Unlock()
+
+	s.dispatchEvent(enums.OnSessionSuspend, WSMessage{
+		Type:     enums.MsgTypeBreak,
+		ClientID: s.clientID,
+		TargetID: targetID,
+		MsgData:  enums.RetCodeDisconnect.String(),
+	}, nil)
+}
+
+func (s *Session) isResumableLocked() bool {
+	return !s.suspendedAt.IsZero() && time.Since(s.suspendedAt) <= s.config.ResumeGrace
+}
+
+// IsSuspended reports whether the session is waiting for the app to reconnect.
+func (s *Session) IsSuspended() bool {
+	s.lock.RLock()
+	defer s.lock.RUnlock()
+
+	return s.isResumableLocked()
+}
+
 func (s *Session) handleHeartBeat(message WSMessage) {
-	s.lastHeartbeatTime = time.Now()
+	s.touch()
 	s.dispatchEvent(enums.OnSessionHeartBeat, message, nil)
 }
 
+// touch records that the app is still alive.
+func (s *Session) touch() {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	s.lastHeartbeatTime = time.Now()
+	s.isStale = false
+}
+
+// superviseHeartbeat sends a heartbeat to the app, marks the session stale
+// when it stays silent for StaleTimeout and evicts it after EvictTimeout.
+func (s *Session) superviseHeartbeat() {
+	s.lock.Lock()
+	if !s.suspendedAt.IsZero() && !s.isResumableLocked() {
+		targetID := s.targetID
+		s.suspendedAt = time.Time{}
+		s.targetID = ""
+		s.strengthData = StrengthData{}
+		s.lock.Unlock()
+
+		zap.L().Info("Session resume grace expired", zap.String("clientID", s.clientID))
+		s.dispatchEvent(enums.OnSessionBreak, WSMessage{
+			Type:     enums.MsgTypeBreak,
+			ClientID: s.clientID,
+			TargetID: targetID,
+			MsgData:  enums.RetCodeDisconnect.String(),
+		}, nil)
+		return
+	}
+	if s.wsSession == nil || s.wsSession.IsClosed() || !s.isBound {
+		s.lock.Unlock()
+		return
+	}
+	silence := time.Since(s.lastHeartbeatTime)
+	targetID := s.targetID
+	becameStale := silence > s.config.staleTimeout() && !s.isStale
+	if becameStale {
+		s.isStale = true
+	}
+	s.lock.Unlock()
+
+	if becameStale {
+		zap.L().Warn("Session stale", zap.String("clientID", s.clientID), zap.Duration("silence", silence))
+		s.dispatchEvent(enums.OnSessionBreak, WSMessage{
+			Type:     enums.MsgTypeBreak,
+			ClientID: s.clientID,
+			TargetID: targetID,
+			MsgData:  enums.RetCodeDisconnect.String(),
+		}, nil)
+	}
+	if silence > s.config.evictTimeout() {
+		zap.L().Warn("Evict stale session", zap.String("clientID", s.clientID))
+		s.Disconnect()
+		return
+	}
+
+	heartbeatMsg := WSMessage{
+		Type:     enums.MsgTypeHeartBeat,
+		ClientID: s.clientID,
+		TargetID: targetID,
+		MsgData:  enums.RetCodeSuccess.String(),
+	}
+	err := s.SendMessage(heartbeatMsg)
+	if err != nil {
+		zap.L().Error("Failed to send heartbeat", zap.Error(err))
+		return
+	}
+	s.dispatchEvent(enums.OnSessionHeartBeat, heartbeatMsg, nil)
+}
+
 func (s *Session) handleBreak(message WSMessage) {
+	s.lock.Lock()
 	s.targetID = ""
+	s.lock.Unlock()
 	s.dispatchEvent(enums.OnSessionBreak, message, nil)
 }
 
@@ -82,14 +215,17 @@ func (s *Session) updateStrength(message WSMessage) error {
 	if err != nil {
 		return err
 	}
-	s.strengthData = StrengthData{
+	strengthData := StrengthData{
 		StrengthA:    result[0],
 		StrengthB:    result[1],
 		MaxStrengthA: result[2],
 		MaxStrengthB: result[3],
 	}
+	s.lock.Lock()
+	s.strengthData = strengthData
+	s.lock.Unlock()
 
-	s.dispatchEvent(enums.OnSessionStrengthChange, message, s.strengthData)
+	s.dispatchEvent(enums.OnSessionStrengthChange, message, strengthData)
 	return nil
 }
 
@@ -103,7 +239,15 @@ func (s *Session) handleFeedback(message WSMessage) error {
 }
 
 func (s *Session) SendMessage(message WSMessage) error {
-	if s.wsSession == nil {
+	s.lock.RLock()
+	wsSession := s.wsSession
+	s.lock.RUnlock()
+
+	return s.writeMessage(wsSession, message)
+}
+
+func (s *Session) writeMessage(wsSession *melody.Session, message WSMessage) error {
+	if wsSession == nil {
 		return NoWSSessionError{Message: "No Websocket connection bound to this session"}
 	}
 
@@ -116,45 +260,77 @@ func (s *Session) SendMessage(message WSMessage) error {
 			Message: "message length larger than 1950",
 		}
 	}
-	return s.wsSession.Write(jsonMsg)
+	s.writeLock.Lock()
+	defer s.writeLock.Unlock()
+	return wsSession.Write(jsonMsg)
 }
 
 func (s *Session) IsBound() bool {
+	s.lock.RLock()
+	defer s.lock.RUnlock()
+
+	return s.isBoundLocked()
+}
+
+func (s *Session) isBoundLocked() bool {
 	if s.wsSession == nil {
 		return false
 	}
-	// 未实装的 heartbeat ?
-	//if time.Since(s.lastHeartbeatTime) > 2*time.Second {
-	//	return false
-	//}
+	if time.Since(s.lastHeartbeatTime) > s.config.staleTimeout() {
+		return false
+	}
 	return !s.wsSession.IsClosed() && s.isBound
 }
 
 func (s *Session) Disconnect() {
-	if s.wsSession != nil && !s.wsSession.IsClosed() {
-		_ = s.SendMessage(WSMessage{
+	s.lock.Lock()
+	wsSession := s.wsSession
+	s.isBound = false
+	s.targetID = ""
+	s.suspendedAt = time.Time{}
+	s.lock.Unlock()
+
+	if wsSession != nil && !wsSession.IsClosed() {
+		_ = s.writeMessage(wsSession, WSMessage{
 			ClientID: s.clientID,
 			Type:     enums.MsgTypeBreak,
 			TargetID: s.clientID,
 			MsgData:  "",
 		})
-		_ = s.wsSession.Close()
+		_ = wsSession.Close()
 	}
-	s.isBound = false
-	s.targetID = ""
 }
 
 func (s *Session) SetWSSession(wsSession *melody.Session) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
 	s.wsSession = wsSession
 	s.wsSession.Set("clientID", s.clientID)
+	s.lastHeartbeatTime = time.Now()
+	s.isStale = false
+}
+
+// ownsWSSession reports whether wsSession is the connection currently bound to s.
+func (s *Session) ownsWSSession(wsSession *melody.Session) bool {
+	s.lock.RLock()
+	defer s.lock.RUnlock()
+
+	return s.wsSession == wsSession
 }
 
-func (s *Session) RegisterCallback(eventType enums.SessionEvent, callback func(session *Session, callbackData CallbackData[any])) {
+func (s *Session) RegisterCallback(eventType enums.SessionEvent, callback SessionCallback) {
+	s.callbackLock.Lock()
+	defer s.callbackLock.Unlock()
+
 	s.callbacks[eventType] = append(s.callbacks[eventType], callback)
 }
 
 func (s *Session) dispatchEvent(eventType enums.SessionEvent, message WSMessage, data any) {
+	s.callbackLock.RLock()
 	callbacks := s.callbacks[eventType]
+	s.callbackLock.RUnlock()
+
 	for _, callback := range callbacks {
 		callbackData := CallbackData[any]{
 			Message:      &message,
@@ -165,14 +341,22 @@ func (s *Session) dispatchEvent(eventType enums.SessionEvent, message WSMessage,
 }
 
 func (s *Session) GetQRCodeContent() string {
-	uri := fmt.Sprintf("ws://%s:%d", s.config.Address, s.config.Port)
+	uri := fmt.Sprintf("%s://%s:%d", s.config.scheme(), s.config.Address, s.config.Port)
 	return fmt.Sprintf("https://www.dungeon-lab.com/app-download.php#DGLAB-SOCKET#%s/%s", uri, s.clientID)
 }
 
-func (s *Session) WaitForBind() {
+func (s *Session) WaitForBind(ctx context.Context) error {
+	ticker := time.NewTicker(time.Millisecond * 1000)
+	defer ticker.Stop()
+
 	for !s.IsBound() {
-		time.Sleep(time.Millisecond * 1000)
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-ticker.C:
+		}
 	}
+	return nil
 }
 
 func (s *Session) SetStrength(channel enums.ChannelType, action enums.StrengthAction, strength int) error {
@@ -184,8 +368,8 @@ func (s *Session) SetStrength(channel enums.ChannelType, action enums.StrengthAc
 
 	msg := WSMessage{
 		ClientID: s.clientID,
-		TargetID: s.targetID,
-		MsgData:  fmt.Sprintf("strength-%d+%d+%d", channel, action, strength),
+		TargetID: s.GetTargetID(),
+		MsgData:  FormatStrengthMsg(channel, action, strength),
 		Type:     enums.MsgTypeMessage,
 	}
 
@@ -200,8 +384,8 @@ func (s *Session) ClearPulse(channel enums.ChannelType) error {
 	}
 	msg := WSMessage{
 		ClientID: s.clientID,
-		TargetID: s.targetID,
-		MsgData:  fmt.Sprintf("clear-%d", channel),
+		TargetID: s.GetTargetID(),
+		MsgData:  FormatClearMsg(channel),
 		Type:     enums.MsgTypeMessage,
 	}
 
@@ -218,57 +402,47 @@ func (s *Session) AddPulse(channel enums.ChannelType, waveform PulseWaveform) er
 		return nil
 	}
 
-	rawPulseData, err := waveform.Marshal()
-	if err != nil {
-		return err
-	}
-	pulseData, err := json.Marshal(rawPulseData)
+	msgData, err := FormatPulseMsg(channel, waveform)
 	if err != nil {
 		return err
 	}
 
 	msg := WSMessage{
 		ClientID: s.clientID,
-		TargetID: s.targetID,
-		MsgData:  fmt.Sprintf("pulse-%s:%s", channel.String(), strings.ReplaceAll(string(pulseData), `\`, "")),
+		TargetID: s.GetTargetID(),
+		MsgData:  msgData,
 		Type:     enums.MsgTypeMessage,
 	}
 	return s.SendMessage(msg)
 }
 
 func (s *Session) AddPulseFrame(channel enums.ChannelType, frames PulseFrame) error {
-	if !s.IsBound() {
-		return NotBindError{
-			Message: "Session is not bound",
-		}
-	}
-	rawPulseData, err := frames.Marshal()
-	if err != nil {
-		return err
-	}
-	pulseData, err := json.Marshal([]string{rawPulseData})
-	if err != nil {
-		return err
-	}
-
-	msg := WSMessage{
-		ClientID: s.clientID,
-		TargetID: s.targetID,
-		MsgData:  fmt.Sprintf("pulse-%s:%s", channel.String(), strings.ReplaceAll(string(pulseData), `\`, "")),
-		Type:     enums.MsgTypeMessage,
-	}
-	return s.SendMessage(msg)
+	return s.AddPulse(channel, PulseWaveform{frames})
 }
 
 func (s *Session) GetStrengthData() StrengthData {
+	s.lock.RLock()
+	defer s.lock.RUnlock()
+
 	return s.strengthData
 }
 
+func (s *Session) GetClientID() string {
+	return s.clientID
+}
+
+func (s *Session) GetTargetID() string {
+	s.lock.RLock()
+	defer s.lock.RUnlock()
+
+	return s.targetID
+}
+
 func NewCoyoteSession(clientID string, config *Config) *Session {
 	return &Session{
 		clientID: clientID,
 		config:   config,
 
-		callbacks: make(map[enums.SessionEvent][]func(s *Session, callbackData CallbackData[any])),
+		callbacks: make(map[enums.SessionEvent][]SessionCallback),
 	}
 }
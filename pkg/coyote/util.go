@@ -1,10 +1,13 @@
 package coyote
 
 import (
+	"IsaacCoyote/pkg/coyote/enums"
 	"encoding/hex"
 	"encoding/json"
+	"fmt"
 	"regexp"
 	"strconv"
+	"strings"
 )
 
 // ParseStrengthData
@@ -85,3 +88,29 @@ func UnmarshalPulseFromString(pulseString string) (PulseWaveform, error) {
 	}
 	return UnmarshalPulseWaveform(data)
 }
+
+// FormatStrengthMsg
+// build the message of a strength operation: strength-channel+action+value
+func FormatStrengthMsg(channel enums.ChannelType, action enums.StrengthAction, strength int) string {
+	return fmt.Sprintf("strength-%d+%d+%d", channel, action, strength)
+}
+
+// FormatClearMsg
+// build the message that clears the pulse queue of channel
+func FormatClearMsg(channel enums.ChannelType) string {
+	return fmt.Sprintf("clear-%d", channel)
+}
+
+// FormatPulseMsg
+// build the message that appends waveform to the pulse queue of channel
+func FormatPulseMsg(channel enums.ChannelType, waveform PulseWaveform) (string, error) {
+	rawPulseData, err := waveform.Marshal()
+	if err != nil {
+		return "", err
+	}
+	pulseData, err := json.Marshal(rawPulseData)
+	if err != nil {
+		return "", err
+	}
+	return fmt.Sprintf("pulse-%s:%s", channel.String(), strings.ReplaceAll(string(pulseData), `\`, "")), nil
+}
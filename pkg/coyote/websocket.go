@@ -1,54 +1,147 @@
 package coyote
 
 import (
+	"context"
+	"errors"
 	"fmt"
 	"github.com/olahol/melody"
 	"go.uber.org/zap"
+	"net"
 	"net/http"
+	"sync"
 )
 
 type MsgHandler func(s *melody.Session, msg []byte)
 type DisconnectHandler func(s *melody.Session)
 type ConnectHandler func(s *melody.Session)
+type PongHandler func(s *melody.Session)
 
 type Server struct {
-	IsRunning bool
+	mux *http.ServeMux
 
-	melody *melody.Melody
+	// serverLock guards melody and httpServer, httpServer is set while the server runs
+	serverLock sync.Mutex
+	melody     *melody.Melody
+	httpServer *http.Server
 
 	config            *Config
 	msgHandler        MsgHandler
 	connHandler       ConnectHandler
 	disconnectHandler DisconnectHandler
+	pongHandler       PongHandler
 }
 
-func (s *Server) Run() error {
-	http.HandleFunc("/", func(w http.ResponseWriter, r *http.Request) {
-		err := s.melody.HandleRequest(w, r)
+// Run serves the websocket endpoint until ctx is cancelled or Shutdown is called.
+func (s *Server) Run(ctx context.Context) error {
+	httpServer := &http.Server{
+		Handler: s.mux,
+	}
+	if s.config.TLS.Enabled {
+		tlsConfig, err := loadTLSConfig(s.config)
 		if err != nil {
-			zap.L().Error("Failed to handle request", zap.Error(err))
+			return err
+		}
+		httpServer.TLSConfig = tlsConfig
+	}
+
+	s.serverLock.Lock()
+	if s.httpServer != nil {
+		s.serverLock.Unlock()
+		return AlreadyRunningError{
+			Message: "  already running",
 		}
-	})
+	}
+	listener, err := net.Listen("tcp", fmt.Sprintf(":%d", s.config.Port))
+	if err != nil {
+		s.serverLock.Unlock()
+		return err
+	}
+	s.melody = melody.New()
+	s.melody.Config.PingPeriod = s.config.heartbeatInterval()
+	s.melody.Config.PongWait = max(s.config.evictTimeout(), 2*s.config.heartbeatInterval())
+	s.melody.Config.MaxMessageSize = maxMessageSize
 	s.melody.HandleMessage(s.msgHandler)
 	s.melody.HandleConnect(s.connHandler)
 	s.melody.HandleDisconnect(s.disconnectHandler)
+	s.melody.HandlePong(s.pongHandler)
+	s.httpServer = httpServer
+	s.serverLock.Unlock()
 
-	s.IsRunning = true
-	err := http.ListenAndServe(fmt.Sprintf(":%d", s.config.Port), nil)
-	if err != nil {
-		s.IsRunning = false
+	errCh := make(chan error, 1)
+	go func() {
+		if httpServer.TLSConfig != nil {
+			errCh <- httpServer.ServeTLS(listener, "", "")
+			return
+		}
+		errCh <- httpServer.Serve(listener)
+	}()
+
+	select {
+	case err := <-errCh:
+		s.serverLock.Lock()
+		if s.httpServer == httpServer {
+			s.httpServer = nil
+		}
+		s.serverLock.Unlock()
+		if errors.Is(err, http.ErrServerClosed) {
+			return nil
+		}
+		return err
+	case <-ctx.Done():
+		shutdownCtx, cancel := context.WithTimeout(context.Background(), defaultShutdownTimeout)
+		defer cancel()
+		err := s.Shutdown(shutdownCtx)
+		<-errCh
 		return err
 	}
-	return nil
 }
 
-func NewCoyoteServer(config *Config, connHandler ConnectHandler, disconnectHandler DisconnectHandler, msgHandler MsgHandler) *Server {
-	return &Server{
+// Shutdown closes all websocket connections and stops the http server.
+func (s *Server) Shutdown(ctx context.Context) error {
+	s.serverLock.Lock()
+	defer s.serverLock.Unlock()
+
+	if s.httpServer == nil {
+		return nil
+	}
+
+	err := s.melody.Close()
+	if err != nil && !errors.Is(err, melody.ErrClosed) {
+		zap.L().Error("Failed to close websocket sessions", zap.Error(err))
+	}
+	err = s.httpServer.Shutdown(ctx)
+	s.httpServer = nil
+	return err
+}
+
+func (s *Server) IsRunning() bool {
+	s.serverLock.Lock()
+	defer s.serverLock.Unlock()
+
+	return s.httpServer != nil
+}
+
+func (s *Server) handleRequest(w http.ResponseWriter, r *http.Request) {
+	s.serverLock.Lock()
+	m := s.melody
+	s.serverLock.Unlock()
+
+	err := m.HandleRequest(w, r)
+	if err != nil {
+		zap.L().Error("Failed to handle request", zap.Error(err))
+	}
+}
+
+func NewCoyoteServer(config *Config, connHandler ConnectHandler, disconnectHandler DisconnectHandler, msgHandler MsgHandler, pongHandler PongHandler) *Server {
+	s := &Server{
 		config: config,
+		mux:    http.NewServeMux(),
 
-		melody:            melody.New(),
 		msgHandler:        msgHandler,
 		connHandler:       connHandler,
 		disconnectHandler: disconnectHandler,
+		pongHandler:       pongHandler,
 	}
+	s.mux.HandleFunc("/", s.handleRequest)
+	return s
 }
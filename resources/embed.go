@@ -0,0 +1,8 @@
+// Package resources holds the default item database, it is compiled into the
+// binary so the tool runs from any working directory.
+package resources
+
+import "embed"
+
+//go:embed *.json
+var FS embed.FS
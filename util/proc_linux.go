@@ -0,0 +1,154 @@
+//go:build linux
+
+package util
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+// procRoot is where the proc filesystem is mounted.
+var procRoot = "/proc"
+
+// GetProcPID finds a process by its executable name. Windows programs running
+// under Wine or Proton are matched by the exe name in their command line.
+func GetProcPID(processName string) (uint32, error) {
+	entries, err := os.ReadDir(procRoot)
+	if err != nil {
+		return 0, err
+	}
+
+	for _, entry := range entries {
+		pid, err := strconv.ParseUint(entry.Name(), 10, 32)
+		if err != nil || !entry.IsDir() {
+			continue
+		}
+		if matchProcName(uint32(pid), processName) {
+			return uint32(pid), nil
+		}
+	}
+	return 0, fmt.Errorf("no process: %s", processName)
+}
+
+func matchProcName(pid uint32, processName string) bool {
+	comm, err := os.ReadFile(procFile(pid, "comm"))
+	if err == nil && strings.EqualFold(strings.TrimSpace(string(comm)), processName) {
+		return true
+	}
+
+	args, err := readProcArgs(pid)
+	if err != nil || len(args) == 0 {
+		return false
+	}
+	return strings.EqualFold(windowsBase(args[0]), processName)
+}
+
+// GetProcPath returns the host path of the executable. For a Wine process the
+// windows path in its command line is resolved through the dosdevices of its prefix.
+func GetProcPath(pid uint32) (string, error) {
+	args, err := readProcArgs(pid)
+	if err != nil {
+		return "", err
+	}
+	if len(args) == 0 {
+		return "", fmt.Errorf("empty command line: %d", pid)
+	}
+	exePath := args[0]
+
+	if isWindowsPath(exePath) {
+		hostPath, err := resolveWinePath(pid, exePath)
+		if err == nil {
+			return hostPath, nil
+		}
+	} else if filepath.IsAbs(exePath) && fileExists(exePath) {
+		return exePath, nil
+	}
+
+	// wine starts programs in their install directory
+	cwd, err := os.Readlink(procFile(pid, "cwd"))
+	if err != nil {
+		return "", err
+	}
+	hostPath := filepath.Join(cwd, windowsBase(exePath))
+	if !fileExists(hostPath) {
+		return "", fmt.Errorf("cant resolve process path: %s", exePath)
+	}
+	return hostPath, nil
+}
+
+// resolveWinePath maps e.g. C:\Games\isaac-ng.exe to <prefix>/dosdevices/c:/Games/isaac-ng.exe.
+func resolveWinePath(pid uint32, winPath string) (string, error) {
+	prefix, err := winePrefix(pid)
+	if err != nil {
+		return "", err
+	}
+
+	drive := strings.ToLower(winPath[:2])
+	rest := strings.ReplaceAll(strings.TrimLeft(winPath[2:], `\/`), `\`, "/")
+	hostPath, err := filepath.EvalSymlinks(filepath.Join(prefix, "dosdevices", drive, rest))
+	if err != nil {
+		return "", err
+	}
+	return hostPath, nil
+}
+
+// winePrefix reads WINEPREFIX from the environment of pid, wine defaults to ~/.wine.
+func winePrefix(pid uint32) (string, error) {
+	environ, err := os.ReadFile(procFile(pid, "environ"))
+	if err != nil {
+		return "", err
+	}
+
+	var home string
+	for _, env := range bytes.Split(environ, []byte{0}) {
+		key, value, _ := strings.Cut(string(env), "=")
+		switch key {
+		case "WINEPREFIX":
+			if value != "" {
+				return value, nil
+			}
+		case "HOME":
+			home = value
+		}
+	}
+	if home == "" {
+		return "", fmt.Errorf("no wine prefix: %d", pid)
+	}
+	return filepath.Join(home, ".wine"), nil
+}
+
+func readProcArgs(pid uint32) ([]string, error) {
+	cmdline, err := os.ReadFile(procFile(pid, "cmdline"))
+	if err != nil {
+		return nil, err
+	}
+
+	var args []string
+	for _, arg := range bytes.Split(bytes.TrimRight(cmdline, "\x00"), []byte{0}) {
+		args = append(args, string(arg))
+	}
+	return args, nil
+}
+
+func procFile(pid uint32, name string) string {
+	return filepath.Join(procRoot, strconv.FormatUint(uint64(pid), 10), name)
+}
+
+func isWindowsPath(p string) bool {
+	return len(p) >= 2 && p[1] == ':' &&
+		(p[0] >= 'a' && p[0] <= 'z' || p[0] >= 'A' && p[0] <= 'Z')
+}
+
+// windowsBase is filepath.Base that also splits on backslashes.
+func windowsBase(p string) string {
+	return p[strings.LastIndexAny(p, `/\`)+1:]
+}
+
+func fileExists(p string) bool {
+	info, err := os.Stat(p)
+	return err == nil && !info.IsDir()
+}
@@ -0,0 +1,244 @@
+//go:build linux
+
+package util
+
+import (
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+// fakeProcess is one /proc/<pid> directory, empty fields are left out.
+type fakeProcess struct {
+	comm    string
+	cmdline []string
+	environ []string
+	cwd     string
+}
+
+// useFakeProc points procRoot at a temporary proc tree for the duration of the test.
+func useFakeProc(t *testing.T, processes map[uint32]fakeProcess) {
+	t.Helper()
+	root := t.TempDir()
+	for pid, p := range processes {
+		dir := filepath.Join(root, strconv.FormatUint(uint64(pid), 10))
+		mustMkdir(t, dir)
+		if p.comm != "" {
+			mustWrite(t, filepath.Join(dir, "comm"), p.comm+"\n")
+		}
+		if p.cmdline != nil {
+			mustWrite(t, filepath.Join(dir, "cmdline"), strings.Join(p.cmdline, "\x00")+"\x00")
+		}
+		if p.environ != nil {
+			mustWrite(t, filepath.Join(dir, "environ"), strings.Join(p.environ, "\x00")+"\x00")
+		}
+		if p.cwd != "" {
+			if err := os.Symlink(p.cwd, filepath.Join(dir, "cwd")); err != nil {
+				t.Fatal(err)
+			}
+		}
+	}
+	// entries that are not processes
+	mustMkdir(t, filepath.Join(root, "sys"))
+	mustWrite(t, filepath.Join(root, "uptime"), "1.00 1.00\n")
+
+	oldRoot := procRoot
+	procRoot = root
+	t.Cleanup(func() {
+		procRoot = oldRoot
+	})
+}
+
+func mustMkdir(t *testing.T, dir string) {
+	t.Helper()
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func mustWrite(t *testing.T, path string, content string) {
+	t.Helper()
+	mustMkdir(t, filepath.Dir(path))
+	if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+		t.Fatal(err)
+	}
+}
+
+// winePrefixWithExe creates a wine prefix whose c: drive holds Games/Isaac/isaac-ng.exe.
+func winePrefixWithExe(t *testing.T, prefix string) string {
+	t.Helper()
+	driveC := filepath.Join(prefix, "drive_c")
+	exePath := filepath.Join(driveC, "Games", "Isaac", "isaac-ng.exe")
+	mustWrite(t, exePath, "")
+	mustMkdir(t, filepath.Join(prefix, "dosdevices"))
+	if err := os.Symlink("../drive_c", filepath.Join(prefix, "dosdevices", "c:")); err != nil {
+		t.Fatal(err)
+	}
+	return exePath
+}
+
+func TestGetProcPID(t *testing.T) {
+	tests := []struct {
+		name        string
+		processName string
+		processes   map[uint32]fakeProcess
+		want        uint32
+		wantErr     bool
+	}{
+		{
+			name:        "native process by comm",
+			processName: "isaac-ng",
+			processes: map[uint32]fakeProcess{
+				10: {comm: "bash", cmdline: []string{"/bin/bash"}},
+				42: {comm: "isaac-ng", cmdline: []string{"/opt/isaac/isaac-ng"}},
+			},
+			want: 42,
+		},
+		{
+			name:        "wine process by the exe in its command line",
+			processName: "isaac-ng.exe",
+			processes: map[uint32]fakeProcess{
+				10: {comm: "wineserver", cmdline: []string{"/usr/bin/wineserver"}},
+				77: {comm: "wine64-preloade", cmdline: []string{`C:\Program Files\Isaac\ISAAC-NG.EXE`, "--luadebug"}},
+			},
+			want: 77,
+		},
+		{
+			name:        "unreadable command line is skipped",
+			processName: "isaac-ng.exe",
+			processes: map[uint32]fakeProcess{
+				5: {comm: "kthreadd"},
+			},
+			wantErr: true,
+		},
+		{
+			name:        "no match",
+			processName: "isaac-ng.exe",
+			processes: map[uint32]fakeProcess{
+				10: {comm: "bash", cmdline: []string{"/bin/bash"}},
+			},
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			useFakeProc(t, tt.processes)
+			pid, err := GetProcPID(tt.processName)
+			if tt.wantErr {
+				if err == nil {
+					t.Fatalf("GetProcPID = %d, want error", pid)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if pid != tt.want {
+				t.Fatalf("GetProcPID = %d, want %d", pid, tt.want)
+			}
+		})
+	}
+}
+
+func TestGetProcPath(t *testing.T) {
+	tmp := t.TempDir()
+
+	prefix := filepath.Join(tmp, "prefix")
+	prefixExe := winePrefixWithExe(t, prefix)
+
+	home := filepath.Join(tmp, "home")
+	homeExe := winePrefixWithExe(t, filepath.Join(home, ".wine"))
+
+	installDir := filepath.Join(tmp, "steam", "The Binding of Isaac Rebirth")
+	cwdExe := filepath.Join(installDir, "isaac-ng.exe")
+	mustWrite(t, cwdExe, "")
+
+	nativeExe := filepath.Join(tmp, "opt", "isaac-ng")
+	mustWrite(t, nativeExe, "")
+
+	tests := []struct {
+		name    string
+		process fakeProcess
+		want    string
+		wantErr bool
+	}{
+		{
+			name: "WINEPREFIX dosdevices",
+			process: fakeProcess{
+				cmdline: []string{`C:\Games\Isaac\isaac-ng.exe`},
+				environ: []string{"HOME=" + home, "WINEPREFIX=" + prefix},
+			},
+			want: prefixExe,
+		},
+		{
+			name: "default prefix in HOME",
+			process: fakeProcess{
+				cmdline: []string{`c:/Games/Isaac/isaac-ng.exe`},
+				environ: []string{"WINEPREFIX=", "HOME=" + home},
+			},
+			want: homeExe,
+		},
+		{
+			name: "cwd when the prefix does not have the exe",
+			process: fakeProcess{
+				cmdline: []string{`Z:\mnt\games\isaac-ng.exe`},
+				environ: []string{"WINEPREFIX=" + prefix},
+				cwd:     installDir,
+			},
+			want: cwdExe,
+		},
+		{
+			name: "cwd for a relative command line",
+			process: fakeProcess{
+				cmdline: []string{"isaac-ng.exe", "-windowed"},
+				cwd:     installDir,
+			},
+			want: cwdExe,
+		},
+		{
+			name: "native absolute path",
+			process: fakeProcess{
+				cmdline: []string{nativeExe},
+			},
+			want: nativeExe,
+		},
+		{
+			name: "exe missing from cwd",
+			process: fakeProcess{
+				cmdline: []string{"isaac-ng.exe"},
+				cwd:     tmp,
+			},
+			wantErr: true,
+		},
+		{
+			name:    "empty command line",
+			process: fakeProcess{cmdline: []string{}},
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			useFakeProc(t, map[uint32]fakeProcess{1234: tt.process})
+			got, err := GetProcPath(1234)
+			if tt.wantErr {
+				if err == nil {
+					t.Fatalf("GetProcPath = %q, want error", got)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			// the temporary directory itself may be behind a symlink
+			resolved, _ := filepath.EvalSymlinks(got)
+			want, _ := filepath.EvalSymlinks(tt.want)
+			if resolved != want {
+				t.Fatalf("GetProcPath = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,16 @@
+//go:build !windows && !linux
+
+package util
+
+import (
+	"fmt"
+	"runtime"
+)
+
+func GetProcPID(processName string) (uint32, error) {
+	return 0, fmt.Errorf("process discovery is not supported on %s", runtime.GOOS)
+}
+
+func GetProcPath(pid uint32) (string, error) {
+	return "", fmt.Errorf("process discovery is not supported on %s", runtime.GOOS)
+}
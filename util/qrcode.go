@@ -4,6 +4,7 @@ import (
 	"github.com/skip2/go-qrcode"
 	"os"
 	"os/exec"
+	"runtime"
 )
 
 func PrintTerminalQRCode(content string) error {
@@ -27,5 +28,12 @@ func ShowQRCode(fileName string, content string) error {
 	if err != nil {
 		return err
 	}
-	return exec.Command("cmd", "/c", "start", "", fileName).Run()
+	switch runtime.GOOS {
+	case "windows":
+		return exec.Command("cmd", "/c", "start", "", fileName).Run()
+	case "darwin":
+		return exec.Command("open", fileName).Run()
+	default:
+		return exec.Command("xdg-open", fileName).Run()
+	}
 }